@@ -24,4 +24,6 @@ type bq interface {
 	EnsureDataset(ctx context.Context, datasetName string) error
 	EnsureTable(ctx context.Context, tableName string) error
 	WriteRow(ctx context.Context, r *bqRow) error
+	// CheckTable returns an error iff the table (as of the last EnsureTable) cannot be found.
+	CheckTable(ctx context.Context) error
 }
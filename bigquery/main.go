@@ -17,6 +17,7 @@ package main
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math/big"
@@ -29,7 +30,6 @@ import (
 	"cloud.google.com/go/civil"
 	cbpb "cloud.google.com/go/cloudbuild/apiv1/v2/cloudbuildpb"
 	"github.com/GoogleCloudPlatform/cloud-build-notifiers/lib/notifiers"
-	log "github.com/golang/glog"
 	"github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/google"
@@ -53,7 +53,7 @@ const megaByte = int64(1000000)
 
 func main() {
 	if err := notifiers.Main(&bqNotifier{bqf: &actualBQFactory{}}); err != nil {
-		log.Fatalf("fatal error: %v", err)
+		notifiers.Fatalf(context.Background(), "fatal error: %v", err)
 	}
 }
 
@@ -168,8 +168,10 @@ func (n *bqNotifier) SetUp(ctx context.Context, cfg *notifiers.Config, bigQueryJ
 
 	// Initialize client
 	n.filter = prd
-	n.client, err = n.bqf.Make(ctx)
-	if err != nil {
+	if notifiers.DryRun() {
+		// Rows are captured instead of written, so no client (or dataset, or table) is needed.
+		n.client = new(dryRunBQ)
+	} else if n.client, err = n.bqf.Make(ctx); err != nil {
 		return fmt.Errorf("failed to initialize bigquery client: %v", err)
 	}
 
@@ -185,13 +187,18 @@ func (n *bqNotifier) SetUp(ctx context.Context, cfg *notifiers.Config, bigQueryJ
 		return err
 	}
 
-	tmpl, err := template.New("bq_json_template").Parse(bigQueryJson)
+	tmpl, err := template.New("bq_json_template").Funcs(notifiers.TemplateFuncs()).Parse(bigQueryJson)
 	n.tmpl = tmpl
 	n.br = br
 
 	return nil
 }
 
+// CheckHealth returns an error iff the BigQuery table cannot be found.
+func (n *bqNotifier) CheckHealth(ctx context.Context) error {
+	return n.client.CheckTable(ctx)
+}
+
 func parsePBTime(time *timestamppb.Timestamp) (civil.DateTime, error) {
 	if time == nil {
 		return civil.DateTime{}, fmt.Errorf("timestamp is nil")
@@ -202,27 +209,54 @@ func parsePBTime(time *timestamppb.Timestamp) (civil.DateTime, error) {
 
 func (n *bqNotifier) SendNotification(ctx context.Context, build *cbpb.Build) error {
 	if !n.filter.Apply(ctx, build) {
-		log.V(2).Infof("not doing BQ write for build %v", build.Id)
+		notifiers.Debugf(ctx, "not doing BQ write for build %v", build.Id)
 		return nil
 	}
 	if build.BuildTriggerId == "" {
-		log.Warningf("build passes filter but does not have a trigger ID. Build id: %q, status: %v", build.Id, build.GetStatus())
+		notifiers.Warningf(ctx, "build passes filter but does not have a trigger ID. Build id: %q, status: %v", build.Id, build.GetStatus())
 	}
 	if !terminalStatusCodes[build.Status] {
-		log.Infof("not writing to BigQuery for non-terminal build status %v", build.Status.String())
+		notifiers.Infof(ctx, "not writing to BigQuery for non-terminal build status %v", build.Status.String())
 		return nil
 	}
-	log.Infof("sending Big Query write for build %q (status: %q)", build.Id, build.Status)
+	notifiers.Infof(ctx, "sending Big Query write for build %q (status: %q)", build.Id, build.Status)
+	newRow, err := n.row(ctx, build, true)
+	if err != nil {
+		return err
+	}
+	wctx, span := notifiers.StartSpan(ctx, "bigquery.WriteRow")
+	err = n.client.WriteRow(wctx, newRow)
+	notifiers.EndSpan(span, err)
+	return err
+}
+
+// Render returns the row that would be inserted for the given Build as JSON. Its images are not looked up in the
+// registry, so the row has none.
+func (n *bqNotifier) Render(ctx context.Context, build *cbpb.Build) ([]byte, error) {
+	newRow, err := n.row(ctx, build, false)
+	if err != nil {
+		return nil, err
+	}
+	j, err := json.MarshalIndent(newRow, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode row: %w", err)
+	}
+	return append(j, '\n'), nil
+}
+
+// row returns the row to insert for the given Build. The images of a successful Build are looked up in the registry
+// iff lookUpImages is true.
+func (n *bqNotifier) row(ctx context.Context, build *cbpb.Build, lookUpImages bool) (*bqRow, error) {
 	if build.ProjectId == "" {
-		return fmt.Errorf("build missing project id")
+		return nil, fmt.Errorf("build missing project id")
 	}
 	buildImages := []*buildImage{}
 	shaSet := make(map[string]bool)
-	if build.Status == cbpb.Build_SUCCESS {
+	if lookUpImages && build.Status == cbpb.Build_SUCCESS {
 		for _, image := range build.GetImages() {
 			buildImage, err := imageManifestToBuildImage(image)
 			if err != nil {
-				return fmt.Errorf("error parsing image manifest: %v", err)
+				return nil, fmt.Errorf("error parsing image manifest: %v", err)
 			}
 			if shaSet[buildImage.SHA] {
 				continue
@@ -234,15 +268,15 @@ func (n *bqNotifier) SendNotification(ctx context.Context, build *cbpb.Build) er
 	buildSteps := []*buildStep{}
 	createTime, err := parsePBTime(build.CreateTime)
 	if err != nil {
-		return fmt.Errorf("error parsing CreateTime: %v", err)
+		return nil, fmt.Errorf("error parsing CreateTime: %v", err)
 	}
 	startTime, err := parsePBTime(build.StartTime)
 	if err != nil {
-		return fmt.Errorf("error parsing StartTime: %v", err)
+		return nil, fmt.Errorf("error parsing StartTime: %v", err)
 	}
 	finishTime, err := parsePBTime(build.FinishTime)
 	if err != nil {
-		return fmt.Errorf("error parsing FinishTime: %v", err)
+		return nil, fmt.Errorf("error parsing FinishTime: %v", err)
 	}
 	unixZeroTimestamp := timestamppb.New(time.Unix(0, 0))
 	for _, step := range build.GetSteps() {
@@ -256,11 +290,11 @@ func (n *bqNotifier) SendNotification(ctx context.Context, build *cbpb.Build) er
 		}
 		startTime, err := parsePBTime(st)
 		if err != nil {
-			return fmt.Errorf("error parsing StartTime: %v", err)
+			return nil, fmt.Errorf("error parsing StartTime: %v", err)
 		}
 		endTime, err := parsePBTime(et)
 		if err != nil {
-			return fmt.Errorf("error parsing EndTime: %v", err)
+			return nil, fmt.Errorf("error parsing EndTime: %v", err)
 		}
 		newStep := &buildStep{
 			Name:      step.Name,
@@ -274,7 +308,7 @@ func (n *bqNotifier) SendNotification(ctx context.Context, build *cbpb.Build) er
 	}
 	logURL, err := notifiers.AddUTMParams(build.LogUrl, notifiers.StorageMedium)
 	if err != nil {
-		return fmt.Errorf("error generating UTM params: %v", err)
+		return nil, fmt.Errorf("error generating UTM params: %v", err)
 	}
 	substitutions := []*substitution{}
 	for key, value := range build.Substitutions {
@@ -284,17 +318,17 @@ func (n *bqNotifier) SendNotification(ctx context.Context, build *cbpb.Build) er
 	if n.br != nil {
 		bindings, err = n.br.Resolve(ctx, nil, build)
 		if err != nil {
-			return fmt.Errorf("failed to resolve bindings: %w", err)
+			return nil, fmt.Errorf("failed to resolve bindings: %w", err)
 		}
 	}
 
-	n.tmplView = &notifiers.TemplateView{
-		Build:  &notifiers.BuildView{Build: build},
-		Params: bindings,
-	}
+	n.tmplView = notifiers.NewTemplateView(ctx, build, bindings)
 	var buf bytes.Buffer
-	if err := n.tmpl.Execute(&buf, n.tmplView); err != nil {
-		return err
+	_, span := notifiers.StartSpan(ctx, "template.Execute")
+	err = n.tmpl.Execute(&buf, n.tmplView)
+	notifiers.EndSpan(span, err)
+	if err != nil {
+		return nil, err
 	}
 
 	newRow := &bqRow{
@@ -308,19 +342,20 @@ func (n *bqNotifier) SendNotification(ctx context.Context, build *cbpb.Build) er
 		StartTime:      startTime,
 		FinishTime:     finishTime,
 		Tags:           build.Tags,
-		Env:            build.Options.Env,
+		Env:            build.GetOptions().GetEnv(),
 		LogURL:         logURL,
 		Substitutions:  substitutions,
 		JSON:           buf.String(),
 	}
-	return n.client.WriteRow(ctx, newRow)
+	return newRow, nil
 }
+
 func (bq *actualBQ) EnsureDataset(ctx context.Context, datasetName string) error {
 	// Check for existence of dataset, create if false
 	bq.dataset = bq.client.Dataset(datasetName)
 	_, err := bq.client.Dataset(datasetName).Metadata(ctx)
 	if err != nil {
-		log.Warningf("error obtaining dataset metadata: %v;Creating new BigQuery dataset: %q", err, datasetName)
+		notifiers.Warningf(ctx, "error obtaining dataset metadata: %v;Creating new BigQuery dataset: %q", err, datasetName)
 		if err := bq.dataset.Create(ctx, &bigquery.DatasetMetadata{
 			Name: datasetName, Description: "BigQuery Notifier Build Data",
 		}); err != nil {
@@ -339,13 +374,13 @@ func (bq *actualBQ) EnsureTable(ctx context.Context, tableName string) error {
 	}
 	metadata, err := bq.dataset.Table(tableName).Metadata(ctx)
 	if err != nil {
-		log.Warningf("Error obtaining table metadata: %q;Creating new BigQuery table: %q", err, tableName)
+		notifiers.Warningf(ctx, "Error obtaining table metadata: %q;Creating new BigQuery table: %q", err, tableName)
 		// Create table if it does not exist.
 		if err := bq.table.Create(ctx, &bigquery.TableMetadata{Name: tableName, Description: "BigQuery Notifier Build Data Table", Schema: schema}); err != nil {
 			return fmt.Errorf("failed to initialize table %v: ", err)
 		}
 	} else if len(metadata.Schema) == 0 {
-		log.Warningf("No schema found for table, writing new schema for table: %v", tableName)
+		notifiers.Warningf(ctx, "No schema found for table, writing new schema for table: %v", tableName)
 		update := bigquery.TableMetadataToUpdate{
 			Schema: schema,
 		}
@@ -357,11 +392,46 @@ func (bq *actualBQ) EnsureTable(ctx context.Context, tableName string) error {
 	return nil
 }
 
+func (bq *actualBQ) CheckTable(ctx context.Context) error {
+	if _, err := bq.table.Metadata(ctx); err != nil {
+		return fmt.Errorf("failed to get metadata of table %q: %w", bq.table.FullyQualifiedName(), err)
+	}
+	return nil
+}
+
 func (bq *actualBQ) WriteRow(ctx context.Context, row *bqRow) error {
 	ins := bq.table.Inserter()
-	log.V(2).Infof("Writing row: %v", row)
+	notifiers.Debugf(ctx, "Writing row: %v", row)
 	if err := ins.Put(ctx, row); err != nil {
 		return fmt.Errorf("error inserting row into BQ: %v", err)
 	}
 	return nil
 }
+
+// dryRunBQ captures rows instead of writing them when the notifier runs in dry-run mode.
+type dryRunBQ struct {
+	dataset string
+	table   string
+}
+
+func (bq *dryRunBQ) EnsureDataset(_ context.Context, datasetName string) error {
+	bq.dataset = datasetName
+	return nil
+}
+
+func (bq *dryRunBQ) EnsureTable(_ context.Context, tableName string) error {
+	bq.table = tableName
+	return nil
+}
+
+func (bq *dryRunBQ) CheckTable(_ context.Context) error {
+	return nil
+}
+
+func (bq *dryRunBQ) WriteRow(ctx context.Context, row *bqRow) error {
+	j, err := json.Marshal(row)
+	if err != nil {
+		return fmt.Errorf("failed to encode row: %w", err)
+	}
+	return notifiers.CaptureDelivery(ctx, "bigquery", fmt.Sprintf("%s.%s", bq.dataset, bq.table), j)
+}
@@ -16,6 +16,7 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -27,7 +28,6 @@ import (
 	"cloud.google.com/go/bigquery"
 	cbpb "cloud.google.com/go/cloudbuild/apiv1/v2/cloudbuildpb"
 	"github.com/GoogleCloudPlatform/cloud-build-notifiers/lib/notifiers"
-	log "github.com/golang/glog"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/types"
 	"google.golang.org/api/googleapi"
@@ -106,7 +106,7 @@ func (bq *fakeBQ) EnsureDataset(ctx context.Context, datasetName string) error {
 	fakeResponse := fakeBQServerDS[datasetName]
 	err := fakeResponse.fakeError
 	if err != nil {
-		log.Warningf("Error obtaining dataset metadata: %v", err)
+		notifiers.Warningf(ctx, "Error obtaining dataset metadata: %v", err)
 		if strings.Contains(err.Error(), "404") {
 			return nil
 		}
@@ -130,7 +130,7 @@ func (bq *fakeBQ) EnsureTable(ctx context.Context, tableName string) error {
 	fakeResponse := fakeBQServerTable[tableName]
 	err := fakeResponse.fakeError
 	if err != nil {
-		log.Warningf("Error obtaining table metadata: %v", err)
+		notifiers.Warningf(ctx, "Error obtaining table metadata: %v", err)
 		if strings.Contains(err.Error(), "404") {
 			return nil
 		}
@@ -148,6 +148,13 @@ func (bq *fakeBQ) EnsureTable(ctx context.Context, tableName string) error {
 	return nil
 }
 
+func (bq *fakeBQ) CheckTable(ctx context.Context) error {
+	if !bq.validSchema {
+		return errors.New("table not found")
+	}
+	return nil
+}
+
 func (bq *fakeBQ) WriteRow(ctx context.Context, row *bqRow) error {
 	if !bq.validSchema {
 		return errors.New("Error writing to table, invalid schema")
@@ -616,6 +623,51 @@ func TestSendNotification(t *testing.T) {
 	}
 }
 
+func TestRender(t *testing.T) {
+	cfg := &notifiers.Config{
+		Spec: &notifiers.Spec{
+			Notification: &notifiers.Notification{
+				Filter:   `build.status == Build.Status.SUCCESS`,
+				Delivery: map[string]interface{}{"table": tableURI},
+			},
+		},
+	}
+	fakeBQ := &fakeBQ{}
+	n := &bqNotifier{bqf: &fakeBQFactory{fakeBQ}}
+	if err := n.SetUp(context.Background(), cfg, "{{.Build.Status}}", nil, nil); err != nil {
+		t.Fatalf("Setup(%v) got unexpected error: %v", cfg, err)
+	}
+
+	build := &cbpb.Build{
+		ProjectId:  "Project ID",
+		Id:         "Build ID",
+		Status:     cbpb.Build_SUCCESS,
+		CreateTime: timestamppb.Now(),
+		StartTime:  timestamppb.Now(),
+		FinishTime: timestamppb.Now(),
+		// Images are not looked up when rendering.
+		Images: []string{"gcr.io/no-such-project/no-such-image"},
+	}
+	payload, err := n.Render(context.Background(), build)
+	if err != nil {
+		t.Fatalf("Render(%v) got unexpected error: %v", build, err)
+	}
+	var row bqRow
+	if err := json.Unmarshal(payload, &row); err != nil {
+		t.Fatalf("failed to decode rendered row %q: %v", payload, err)
+	}
+	if row.ID != "Build ID" || row.Status != "SUCCESS" || row.JSON != "SUCCESS" || len(row.Images) != 0 {
+		t.Errorf("Render(%v) = %s, want the row of the Build without images", build, payload)
+	}
+	if len(fakeBQ.writtenRows) != 0 {
+		t.Errorf("unexpected write: %v", fakeBQ.writtenRows)
+	}
+
+	if _, err := n.Render(context.Background(), &cbpb.Build{Id: "Build ID"}); err == nil {
+		t.Error("Render succeeded for a Build without a project ID, want error")
+	}
+}
+
 func TestGetImageSize(t *testing.T) {
 	for _, tc := range []struct {
 		name      string
@@ -20,11 +20,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"strings"
 	"text/template"
 
 	"github.com/GoogleCloudPlatform/cloud-build-notifiers/lib/notifiers"
-	log "github.com/golang/glog"
+	"go.opentelemetry.io/otel/attribute"
 
 	cbpb "cloud.google.com/go/cloudbuild/apiv1/v2/cloudbuildpb"
 )
@@ -36,7 +35,7 @@ const (
 
 func main() {
 	if err := notifiers.Main(new(githubissuesNotifier)); err != nil {
-		log.Fatalf("fatal error: %v", err)
+		notifiers.Fatalf(context.Background(), "fatal error: %v", err)
 	}
 }
 
@@ -69,7 +68,7 @@ func (g *githubissuesNotifier) SetUp(ctx context.Context, cfg *notifiers.Config,
 	}
 	g.githubRepo = repo
 
-	tmpl, err := template.New("issue_template").Parse(issueTemplate)
+	tmpl, err := template.New("issue_template").Funcs(notifiers.TemplateFuncs()).Parse(issueTemplate)
 	if err != nil {
 		return fmt.Errorf("failed to parse issue body template: %w", err)
 	}
@@ -94,44 +93,25 @@ func (g *githubissuesNotifier) SetUp(ctx context.Context, cfg *notifiers.Config,
 
 func (g *githubissuesNotifier) SendNotification(ctx context.Context, build *cbpb.Build) error {
 	if !g.filter.Apply(ctx, build) {
-		log.V(2).Infof("not sending response for event (build id = %s, status = %v)", build.Id, build.Status)
+		notifiers.Debugf(ctx, "not sending response for event (build id = %s, status = %v)", build.Id, build.Status)
 		return nil
 	}
 
 	repo := GetGithubRepo(build)
 	if repo == "" {
-		log.Warningf("could not determine GitHub repository from build, skipping notification")
+		notifiers.Warningf(ctx, "could not determine GitHub repository from build, skipping notification")
 		return nil
 	}
 	webhookURL := fmt.Sprintf("%s/%s/issues", githubApiEndpoint, repo)
 
-	log.Infof("sending GitHub Issue webhook for Build %q (status: %q) to url %q", build.Id, build.Status, webhookURL)
+	notifiers.Infof(ctx, "sending GitHub Issue webhook for Build %q (status: %q) to url %q", build.Id, build.Status, webhookURL)
 
-	bindings, err := g.br.Resolve(ctx, nil, build)
-	if err != nil {
-		log.Errorf("failed to resolve bindings :%v", err)
-	}
-	g.tmplView = &notifiers.TemplateView{
-		Build:  &notifiers.BuildView{Build: build},
-		Params: bindings,
-	}
-	logURL, err := notifiers.AddUTMParams(build.LogUrl, notifiers.HTTPMedium)
+	body, err := g.Render(ctx, build)
 	if err != nil {
-		return fmt.Errorf("failed to add UTM params: %w", err)
-	}
-	build.LogUrl = logURL
-
-	payload := new(bytes.Buffer)
-	var buf bytes.Buffer
-	if err := g.tmpl.Execute(&buf, g.tmplView); err != nil {
 		return err
 	}
-	err = json.NewEncoder(payload).Encode(buf)
-	if err != nil {
-		return fmt.Errorf("failed to encode payload: %w", err)
-	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, strings.NewReader(buf.String()))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
 	if err != nil {
 		return fmt.Errorf("failed to create a new HTTP request: %w", err)
 	}
@@ -140,20 +120,63 @@ func (g *githubissuesNotifier) SendNotification(ctx context.Context, build *cbpb
 	req.Header.Set("Authorization", fmt.Sprintf("token %s", g.githubToken))
 	req.Header.Set("User-Agent", "GCB-Notifier/0.1 (http)")
 
-	resp, err := http.DefaultClient.Do(req)
+	_, span := notifiers.StartSpan(ctx, "http.Post")
+	resp, err := notifiers.DeliveryClient().Do(req)
 	if err != nil {
+		notifiers.EndSpan(span, err)
 		return fmt.Errorf("failed to make HTTP request: %w", err)
 	}
 	defer resp.Body.Close()
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	notifiers.EndSpan(span, nil)
 
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return fmt.Errorf("got response status %q (%d) from %q: %w", resp.Status, resp.StatusCode, webhookURL, notifiers.ErrUnauthorized)
+	}
 	if resp.StatusCode != http.StatusOK {
-		log.Warningf("got a non-OK response status %q (%d) from %q", resp.Status, resp.StatusCode, webhookURL)
+		notifiers.Warningf(ctx, "got a non-OK response status %q (%d) from %q", resp.Status, resp.StatusCode, webhookURL)
 	}
 
-	log.V(2).Infoln("send HTTP request successfully")
+	notifiers.Debugf(ctx, "send HTTP request successfully")
 	return nil
 }
 
+// Render returns the body of the GitHub API request that files the issue for the given Build.
+func (g *githubissuesNotifier) Render(ctx context.Context, build *cbpb.Build) ([]byte, error) {
+	bindings, err := g.br.Resolve(ctx, nil, build)
+	if err != nil {
+		notifiers.Errorf(ctx, "failed to resolve bindings :%v", err)
+	}
+	g.tmplView = notifiers.NewTemplateView(ctx, build, bindings)
+	logURL, err := notifiers.AddUTMParams(build.LogUrl, notifiers.HTTPMedium)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add UTM params: %w", err)
+	}
+	build.LogUrl = logURL
+
+	payload := new(bytes.Buffer)
+	var buf bytes.Buffer
+	_, span := notifiers.StartSpan(ctx, "template.Execute")
+	err = g.tmpl.Execute(&buf, g.tmplView)
+	notifiers.EndSpan(span, err)
+	if err != nil {
+		return nil, err
+	}
+	err = json.NewEncoder(payload).Encode(buf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode payload: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// DestinationKey rate limits the issues of every GitHub repository separately.
+func (g *githubissuesNotifier) DestinationKey(build *cbpb.Build) string {
+	if repo := GetGithubRepo(build); repo != "" {
+		return "github/" + repo
+	}
+	return ""
+}
+
 func GetGithubRepo(build *cbpb.Build) string {
 	if build.Substitutions != nil && build.Substitutions["REPO_FULL_NAME"] != "" {
 		// return repo full name if it's available
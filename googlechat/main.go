@@ -22,7 +22,7 @@ import (
 	"net/http"
 
 	"github.com/GoogleCloudPlatform/cloud-build-notifiers/lib/notifiers"
-	log "github.com/golang/glog"
+	"go.opentelemetry.io/otel/attribute"
 	chat "google.golang.org/api/chat/v1"
 
 	cbpb "cloud.google.com/go/cloudbuild/apiv1/v2/cloudbuildpb"
@@ -34,7 +34,7 @@ const (
 
 func main() {
 	if err := notifiers.Main(new(googlechatNotifier)); err != nil {
-		log.Fatalf("fatal error: %v", err)
+		notifiers.Fatalf(context.Background(), "fatal error: %v", err)
 	}
 }
 
@@ -73,19 +73,13 @@ func (g *googlechatNotifier) SendNotification(ctx context.Context, build *cbpb.B
 		return nil
 	}
 
-	log.Infof("sending Google Chat webhook for Build %q (status: %q)", build.Id, build.Status)
-	msg, err := g.writeMessage(build)
+	notifiers.Infof(ctx, "sending Google Chat webhook for Build %q (status: %q)", build.Id, build.Status)
+	payload, err := g.Render(ctx, build)
 	if err != nil {
-		return fmt.Errorf("failed to write Google Chat message: %w", err)
+		return err
 	}
 
-	payload := new(bytes.Buffer)
-	err = json.NewEncoder(payload).Encode(msg)
-	if err != nil {
-		return fmt.Errorf("failed to encode payload: %w", err)
-	}
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.webhookURL, payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.webhookURL, bytes.NewReader(payload))
 	if err != nil {
 		return fmt.Errorf("failed to create a new HTTP request: %w", err)
 	}
@@ -93,21 +87,43 @@ func (g *googlechatNotifier) SendNotification(ctx context.Context, build *cbpb.B
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("User-Agent", "GCB-Notifier/0.1 (http)")
 
-	resp, err := http.DefaultClient.Do(req)
+	_, span := notifiers.StartSpan(ctx, "http.Post")
+	resp, err := notifiers.DeliveryClient().Do(req)
 	if err != nil {
+		notifiers.EndSpan(span, err)
 		return fmt.Errorf("failed to make HTTP request: %w", err)
 	}
 	defer resp.Body.Close()
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	notifiers.EndSpan(span, nil)
 
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return fmt.Errorf("got response status %q (%d) from %q: %w", resp.Status, resp.StatusCode, g.webhookURL, notifiers.ErrUnauthorized)
+	}
 	if resp.StatusCode != http.StatusOK {
-		log.Warningf("got a non-OK response status %q (%d) from %q", resp.Status, resp.StatusCode, g.webhookURL)
+		notifiers.Warningf(ctx, "got a non-OK response status %q (%d) from %q", resp.Status, resp.StatusCode, g.webhookURL)
 	}
 
-	log.V(2).Infoln("send HTTP request successfully")
+	notifiers.Debugf(ctx, "send HTTP request successfully")
 	return nil
 }
 
-func (g *googlechatNotifier) writeMessage(build *cbpb.Build) (*chat.Message, error) {
+// Render returns the JSON of the webhook message for the given Build.
+func (g *googlechatNotifier) Render(ctx context.Context, build *cbpb.Build) ([]byte, error) {
+	msg, err := g.writeMessage(ctx, build)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write Google Chat message: %w", err)
+	}
+
+	payload := new(bytes.Buffer)
+	err = json.NewEncoder(payload).Encode(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode payload: %w", err)
+	}
+	return payload.Bytes(), nil
+}
+
+func (g *googlechatNotifier) writeMessage(ctx context.Context, build *cbpb.Build) (*chat.Message, error) {
 
 	var icon string
 
@@ -155,7 +171,7 @@ func (g *googlechatNotifier) writeMessage(build *cbpb.Build) (*chat.Message, err
 	// Optional section: display trigger information
 	if build.BuildTriggerId != "" {
 
-		log.Infof("Detected a build trigger id: %s", build.BuildTriggerId)
+		notifiers.Infof(ctx, "Detected a build trigger id: %s", build.BuildTriggerId)
 
 		/*
 			//TODO(glasnt): Get trigger information for Uri links.
@@ -165,7 +181,7 @@ func (g *googlechatNotifier) writeMessage(build *cbpb.Build) (*chat.Message, err
 			ctx := context.Background()
 			cbapi, _ := cloudbuild.NewClient(ctx)
 			trigger_info := cbapi.GetBuildTrigger(ctx, &cbpb.GetBuildTriggerRequest{ProjectId: build.ProjectId, TriggerId: build.BuildTriggerId,})
-			log.Infof("Trigger Repo URI: %s", trigger_info.??)
+			notifiers.Infof(ctx, "Trigger Repo URI: %s", trigger_info.??)
 		*/
 
 		repo_name := build.Substitutions["REPO_NAME"]
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"testing"
 
 	cbpb "cloud.google.com/go/cloudbuild/apiv1/v2/cloudbuildpb"
@@ -18,7 +19,7 @@ func TestWriteMessage(t *testing.T) {
 		LogUrl:    "https://some.example.com/log/url?foo=bar",
 	}
 
-	got, err := n.writeMessage(b)
+	got, err := n.writeMessage(context.Background(), b)
 	if err != nil {
 		t.Fatalf("writeMessage failed: %v", err)
 	}
@@ -19,13 +19,14 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
-	"strings"
+	"net/url"
 	"text/template"
 
 	cbpb "cloud.google.com/go/cloudbuild/apiv1/v2/cloudbuildpb"
 	"github.com/GoogleCloudPlatform/cloud-build-notifiers/lib/notifiers"
-	log "github.com/golang/glog"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 const (
@@ -34,7 +35,7 @@ const (
 
 func main() {
 	if err := notifiers.Main(new(httpNotifier)); err != nil {
-		log.Fatalf("fatal error: %v", err)
+		notifiers.Fatalf(context.Background(), "fatal error: %v", err)
 	}
 }
 
@@ -72,7 +73,7 @@ func (h *httpNotifier) SetUp(ctx context.Context, cfg *notifiers.Config, httpTem
 		h.url = url
 	}
 
-	tmpl, err := template.New("http_template").Parse(httpTemplate)
+	tmpl, err := template.New("http_template").Funcs(notifiers.TemplateFuncs()).Parse(httpTemplate)
 	if err != nil {
 		return fmt.Errorf("failed to parse template: %v", err)
 	}
@@ -83,52 +84,91 @@ func (h *httpNotifier) SetUp(ctx context.Context, cfg *notifiers.Config, httpTem
 
 func (h *httpNotifier) SendNotification(ctx context.Context, build *cbpb.Build) error {
 	if !h.filter.Apply(ctx, build) {
-		log.V(2).Infof("not sending HTTP request for event (build id = %s, status = %v)", build.Id, build.Status)
+		notifiers.Debugf(ctx, "not sending HTTP request for event (build id = %s, status = %v)", build.Id, build.Status)
 		return nil
 	}
 
-	log.Infof("sending HTTP request for event (build id = %s, status = %s)", build.Id, build.Status)
+	notifiers.Infof(ctx, "sending HTTP request for event (build id = %s, status = %s)", build.Id, build.Status)
 
-	bindings, err := h.br.Resolve(ctx, nil, build)
+	body, err := h.Render(ctx, build)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(body))
 	if err != nil {
-		return fmt.Errorf("failed to resolve bindings: %w", err)
+		return fmt.Errorf("failed to create a new HTTP request: %w", err)
 	}
-	h.tmplView = &notifiers.TemplateView{
-		Build:  &notifiers.BuildView{Build: build},
-		Params: bindings,
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "GCB-Notifier/0.1 (http)")
+	_, span := notifiers.StartSpan(ctx, "http.Post")
+	resp, err := notifiers.DeliveryClient().Do(req)
+	if err != nil {
+		notifiers.EndSpan(span, err)
+		return fmt.Errorf("failed to make HTTP request: %w", err)
+	}
+	defer resp.Body.Close()
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	notifiers.EndSpan(span, nil)
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return fmt.Errorf("got response status %q (%d) from %q: %w", resp.Status, resp.StatusCode, h.url, notifiers.ErrUnauthorized)
+	}
+	if resp.StatusCode != http.StatusOK {
+		notifiers.Warningf(ctx, "got a non-OK response status %q (%d) from %q", resp.Status, resp.StatusCode, h.url)
+	}
+
+	notifiers.Debugf(ctx, "send HTTP request successfully")
+	return nil
+}
+
+// Render returns the body of the HTTP request for the given Build.
+func (h *httpNotifier) Render(ctx context.Context, build *cbpb.Build) ([]byte, error) {
+	bindings, err := h.br.Resolve(ctx, nil, build)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve bindings: %w", err)
 	}
+	h.tmplView = notifiers.NewTemplateView(ctx, build, bindings)
 
 	logURL, err := notifiers.AddUTMParams(build.LogUrl, notifiers.HTTPMedium)
 	if err != nil {
-		return fmt.Errorf("failed to add UTM params: %w", err)
+		return nil, fmt.Errorf("failed to add UTM params: %w", err)
 	}
 	build.LogUrl = logURL
 
 	payload := new(bytes.Buffer)
 	var buf bytes.Buffer
-	if err := h.tmpl.Execute(&buf, h.tmplView); err != nil {
-		return err
+	_, span := notifiers.StartSpan(ctx, "template.Execute")
+	err = h.tmpl.Execute(&buf, h.tmplView)
+	notifiers.EndSpan(span, err)
+	if err != nil {
+		return nil, err
 	}
 	err = json.NewEncoder(payload).Encode(buf)
 	if err != nil {
-		return fmt.Errorf("failed to encode payload: %w", err)
+		return nil, fmt.Errorf("failed to encode payload: %w", err)
 	}
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, strings.NewReader(buf.String()))
+	return buf.Bytes(), nil
+}
+
+// CheckHealth returns an error iff the host of the webhook URL cannot be dialed. The webhook itself is not called,
+// since doing so may have side effects.
+func (h *httpNotifier) CheckHealth(ctx context.Context) error {
+	u, err := url.Parse(h.url)
 	if err != nil {
-		return fmt.Errorf("failed to create a new HTTP request: %w", err)
+		return fmt.Errorf("failed to parse webhook URL: %w", err)
 	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("User-Agent", "GCB-Notifier/0.1 (http)")
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to make HTTP request: %w", err)
+	port := u.Port()
+	if port == "" {
+		port = "443"
+		if u.Scheme == "http" {
+			port = "80"
+		}
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		log.Warningf("got a non-OK response status %q (%d) from %q", resp.Status, resp.StatusCode, h.url)
+	// The URL may come from a secret, so only its host is included in errors.
+	conn, err := new(net.Dialer).DialContext(ctx, "tcp", net.JoinHostPort(u.Hostname(), port))
+	if err != nil {
+		return fmt.Errorf("failed to reach webhook host %q: %w", u.Hostname(), err)
 	}
-
-	log.V(2).Infoln("send HTTP request successfully")
-	return nil
+	return conn.Close()
 }
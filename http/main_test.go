@@ -16,9 +16,14 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"text/template"
 
+	cbpb "cloud.google.com/go/cloudbuild/apiv1/v2/cloudbuildpb"
 	"github.com/GoogleCloudPlatform/cloud-build-notifiers/lib/notifiers"
 )
 
@@ -162,3 +167,76 @@ func (f *fakeSecretGetter) GetSecret(_ context.Context, name string) (string, er
 	}
 	return urlSecret, nil
 }
+
+type fakeBindingResolver struct{}
+
+func (f *fakeBindingResolver) Resolve(_ context.Context, _ notifiers.SecretGetter, _ *cbpb.Build) (map[string]string, error) {
+	return nil, nil
+}
+
+func TestSendNotificationStatus(t *testing.T) {
+	for _, tc := range []struct {
+		name             string
+		status           int
+		wantErr          bool
+		wantUnauthorized bool
+	}{{
+		name:   "ok",
+		status: http.StatusOK,
+	}, {
+		name:   "server error is only logged",
+		status: http.StatusInternalServerError,
+	}, {
+		name:             "unauthorized",
+		status:           http.StatusUnauthorized,
+		wantErr:          true,
+		wantUnauthorized: true,
+	}, {
+		name:             "forbidden",
+		status:           http.StatusForbidden,
+		wantErr:          true,
+		wantUnauthorized: true,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(tc.status)
+			}))
+			defer srv.Close()
+
+			filter, err := notifiers.MakeCELPredicate(`build.status == Build.Status.SUCCESS`)
+			if err != nil {
+				t.Fatal(err)
+			}
+			n := &httpNotifier{
+				filter: filter,
+				tmpl:   template.Must(template.New("").Parse(`{"id": "{{.Build.Id}}"}`)),
+				url:    srv.URL,
+				br:     new(fakeBindingResolver),
+			}
+
+			err = n.SendNotification(context.Background(), &cbpb.Build{Id: "some-build", Status: cbpb.Build_SUCCESS})
+			if (err != nil) != tc.wantErr {
+				t.Errorf("SendNotification returned error %v, want error: %v", err, tc.wantErr)
+			}
+			if got := errors.Is(err, notifiers.ErrUnauthorized); got != tc.wantUnauthorized {
+				t.Errorf("errors.Is(%v, ErrUnauthorized) = %v, want %v", err, got, tc.wantUnauthorized)
+			}
+		})
+	}
+}
+
+func TestCheckHealth(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		t.Error("expected the health check not to call the webhook")
+	}))
+	n := &httpNotifier{url: srv.URL + "/notify"}
+
+	if err := n.CheckHealth(context.Background()); err != nil {
+		t.Errorf("CheckHealth failed with a reachable webhook host: %v", err)
+	}
+
+	srv.Close()
+	if err := n.CheckHealth(context.Background()); err == nil {
+		t.Error("expected CheckHealth to fail with an unreachable webhook host")
+	}
+}
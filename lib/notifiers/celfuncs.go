@@ -0,0 +1,203 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifiers
+
+import (
+	"regexp"
+	"time"
+
+	cbpb "cloud.google.com/go/cloudbuild/apiv1/v2/cloudbuildpb"
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common"
+	"github.com/google/cel-go/common/ast"
+	"github.com/google/cel-go/common/operators"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/ext"
+)
+
+// failedStepStatuses are the step statuses that `failedSteps` considers failed.
+var failedStepStatuses = map[cbpb.Build_Status]bool{
+	cbpb.Build_FAILURE:        true,
+	cbpb.Build_INTERNAL_ERROR: true,
+	cbpb.Build_TIMEOUT:        true,
+}
+
+// celNow is the clock of the `now()` CEL function. It is a variable for testing.
+var celNow = time.Now
+
+// celFunctions returns the CEL environment options that declare the functions and macros that filters can use on top
+// of the standard ones. Values (e.g. the steps returned by `failedSteps`) are converted to CEL with the given adapter,
+// which must know the Build types.
+func celFunctions(adapter types.Adapter) []cel.EnvOption {
+	buildType := cel.ObjectType(cloudBuildProtoPkg + ".Build")
+	stepType := cel.ObjectType(cloudBuildProtoPkg + ".BuildStep")
+
+	return []cel.EnvOption{
+		// String extensions, e.g. `s.lowerAscii()`, `s.split(",")`, or `s.indexOf("x")`.
+		ext.Strings(),
+
+		// `duration(build)` is how long the Build ran (or has been running), e.g. `duration(build) > duration("10m")`.
+		cel.Function("duration",
+			cel.Overload("duration_build", []*cel.Type{buildType}, cel.DurationType,
+				cel.UnaryBinding(func(v ref.Val) ref.Val {
+					build, ok := v.Value().(*cbpb.Build)
+					if !ok {
+						return types.MaybeNoSuchOverloadErr(v)
+					}
+					return types.Duration{Duration: buildDuration(build)}
+				}))),
+
+		// `failedSteps(build)` lists the steps that failed or timed out, e.g.
+		// `failedSteps(build).exists(s, s.id.matches("^deploy-"))`.
+		cel.Function("failedSteps",
+			cel.Overload("failedSteps_build", []*cel.Type{buildType}, cel.ListType(stepType),
+				cel.UnaryBinding(func(v ref.Val) ref.Val {
+					build, ok := v.Value().(*cbpb.Build)
+					if !ok {
+						return types.MaybeNoSuchOverloadErr(v)
+					}
+					steps := []*cbpb.BuildStep{}
+					for _, s := range build.GetSteps() {
+						if failedStepStatuses[s.GetStatus()] {
+							steps = append(steps, s)
+						}
+					}
+					return adapter.NativeToValue(steps)
+				}))),
+
+		// `hasTag(build, tag)` is true iff the Build has the given tag.
+		cel.Function("hasTag",
+			cel.Overload("hasTag_build_string", []*cel.Type{buildType, cel.StringType}, cel.BoolType,
+				cel.BinaryBinding(func(lhs, rhs ref.Val) ref.Val {
+					build, ok := lhs.Value().(*cbpb.Build)
+					tag, ok2 := rhs.(types.String)
+					if !ok || !ok2 {
+						return types.MaybeNoSuchOverloadErr(lhs)
+					}
+					for _, t := range build.GetTags() {
+						if t == string(tag) {
+							return types.True
+						}
+					}
+					return types.False
+				}))),
+
+		// `substitution(key, default)` is the Build's substitution with the given key, or the default if it has none.
+		cel.Macros(cel.GlobalMacro("substitution", 2, expandSubstitution)),
+
+		// `s.find(regex)` is the first match of the regex in the string (or ""), and `s.findAll(regex)` lists all of
+		// them, e.g. `build.images.exists(i, i.find("^[^/]+") == "us-docker.pkg.dev")`.
+		cel.Function("find",
+			cel.MemberOverload("string_find_string", []*cel.Type{cel.StringType, cel.StringType}, cel.StringType,
+				cel.BinaryBinding(func(lhs, rhs ref.Val) ref.Val {
+					re, err := compileRegex(rhs)
+					if err != nil {
+						return err
+					}
+					return types.String(re.FindString(string(lhs.(types.String))))
+				}))),
+		cel.Function("findAll",
+			cel.MemberOverload("string_findAll_string", []*cel.Type{cel.StringType, cel.StringType}, cel.ListType(cel.StringType),
+				cel.BinaryBinding(func(lhs, rhs ref.Val) ref.Val {
+					re, err := compileRegex(rhs)
+					if err != nil {
+						return err
+					}
+					matches := re.FindAllString(string(lhs.(types.String)), -1)
+					if matches == nil {
+						matches = []string{}
+					}
+					return types.NewStringList(adapter, matches)
+				}))),
+
+		// `now()` is the current time. Together with the standard `getHours(tz)` and `getDayOfWeek(tz)` timestamp
+		// functions, `timeOfDay(timestamp, tz)` ("HH:MM", which compares like a time) and `isWeekend(timestamp, tz)`
+		// let filters depend on when a Build finished, e.g. `timeOfDay(build.finish_time, "Europe/Berlin") >= "18:00"`.
+		cel.Function("now",
+			cel.Overload("now", []*cel.Type{}, cel.TimestampType,
+				cel.FunctionBinding(func(_ ...ref.Val) ref.Val {
+					return types.Timestamp{Time: celNow()}
+				}))),
+		cel.Function("timeOfDay",
+			cel.Overload("timeOfDay_timestamp_string", []*cel.Type{cel.TimestampType, cel.StringType}, cel.StringType,
+				cel.BinaryBinding(func(lhs, rhs ref.Val) ref.Val {
+					t, err := inTimeZone(lhs, rhs)
+					if err != nil {
+						return err
+					}
+					return types.String(t.Format("15:04"))
+				}))),
+		cel.Function("isWeekend",
+			cel.Overload("isWeekend_timestamp_string", []*cel.Type{cel.TimestampType, cel.StringType}, cel.BoolType,
+				cel.BinaryBinding(func(lhs, rhs ref.Val) ref.Val {
+					t, err := inTimeZone(lhs, rhs)
+					if err != nil {
+						return err
+					}
+					return types.Bool(t.Weekday() == time.Saturday || t.Weekday() == time.Sunday)
+				}))),
+	}
+}
+
+// buildDuration returns how long the given Build ran, or has been running if it has not finished.
+func buildDuration(build *cbpb.Build) time.Duration {
+	if build.GetStartTime() == nil {
+		return 0
+	}
+	end := celNow()
+	if build.GetFinishTime() != nil {
+		end = build.GetFinishTime().AsTime()
+	}
+	return end.Sub(build.GetStartTime().AsTime())
+}
+
+// expandSubstitution expands `substitution(key, default)` to
+// `key in build.substitutions ? build.substitutions[key] : default`.
+func expandSubstitution(eh cel.MacroExprFactory, _ ast.Expr, args []ast.Expr) (ast.Expr, *common.Error) {
+	subs := func() ast.Expr { return eh.NewSelect(eh.NewIdent("build"), "substitutions") }
+	return eh.NewCall(operators.Conditional,
+		eh.NewCall(operators.In, args[0], subs()),
+		eh.NewCall(operators.Index, subs(), eh.Copy(args[0])),
+		args[1],
+	), nil
+}
+
+// compileRegex compiles the given CEL string as a regular expression, or returns a CEL error.
+func compileRegex(v ref.Val) (*regexp.Regexp, ref.Val) {
+	s, ok := v.(types.String)
+	if !ok {
+		return nil, types.MaybeNoSuchOverloadErr(v)
+	}
+	re, err := regexp.Compile(string(s))
+	if err != nil {
+		return nil, types.NewErr("invalid regex %q: %v", string(s), err)
+	}
+	return re, nil
+}
+
+// inTimeZone converts the given CEL timestamp to the given CEL time zone name, or returns a CEL error.
+func inTimeZone(ts, tz ref.Val) (time.Time, ref.Val) {
+	t, ok := ts.Value().(time.Time)
+	name, ok2 := tz.(types.String)
+	if !ok || !ok2 {
+		return time.Time{}, types.MaybeNoSuchOverloadErr(ts)
+	}
+	loc, err := time.LoadLocation(string(name))
+	if err != nil {
+		return time.Time{}, types.NewErr("invalid time zone %q: %v", string(name), err)
+	}
+	return t.In(loc), nil
+}
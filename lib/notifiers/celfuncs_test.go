@@ -0,0 +1,132 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifiers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	cbpb "cloud.google.com/go/cloudbuild/apiv1/v2/cloudbuildpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestCELFunctions(t *testing.T) {
+	// Saturday, 17:30 UTC (18:30 in Berlin).
+	start := time.Date(2026, 1, 3, 17, 30, 0, 0, time.UTC)
+	oldNow := celNow
+	celNow = func() time.Time { return start.Add(time.Hour) }
+	t.Cleanup(func() { celNow = oldNow })
+
+	build := &cbpb.Build{
+		Id:         "some-build-id",
+		Status:     cbpb.Build_FAILURE,
+		StartTime:  timestamppb.New(start),
+		FinishTime: timestamppb.New(start.Add(12 * time.Minute)),
+		Steps: []*cbpb.BuildStep{
+			{Id: "build", Name: "gcr.io/cloud-builders/docker", Status: cbpb.Build_SUCCESS},
+			{Id: "deploy-prod", Name: "gcr.io/cloud-builders/gcloud", Status: cbpb.Build_FAILURE},
+			{Id: "notify", Name: "busybox", Status: cbpb.Build_CANCELLED},
+		},
+		Tags:          []string{"prod", "nightly"},
+		Substitutions: map[string]string{"_ENV": "prod"},
+		Images:        []string{"us-docker.pkg.dev/some-project/some-repo/app:1"},
+	}
+
+	for _, tc := range []struct {
+		filter string
+		want   bool
+	}{
+		{filter: `duration(build) > duration("10m")`, want: true},
+		{filter: `duration(build) > duration("15m")`, want: false},
+		{filter: `failedSteps(build).exists(s, s.id.matches("^deploy-"))`, want: true},
+		{filter: `failedSteps(build).size() == 1 && failedSteps(build)[0].name == "gcr.io/cloud-builders/gcloud"`, want: true},
+		{filter: `hasTag(build, "nightly")`, want: true},
+		{filter: `hasTag(build, "dev")`, want: false},
+		{filter: `substitution("_ENV", "dev") == "prod"`, want: true},
+		{filter: `substitution("_MISSING", "dev") == "dev"`, want: true},
+		{filter: `build.substitutions["_ENV"].upperAscii() == "PROD"`, want: true},
+		{filter: `build.images.exists(i, i.find("^[^/]+") == "us-docker.pkg.dev")`, want: true},
+		{filter: `"a1b22c333".findAll("[0-9]+") == ["1", "22", "333"]`, want: true},
+		{filter: `"abc".find("[0-9]+") == ""`, want: true},
+		{filter: `timeOfDay(build.finish_time, "Europe/Berlin") >= "18:00"`, want: true},
+		{filter: `timeOfDay(build.finish_time, "UTC") >= "18:00"`, want: false},
+		{filter: `isWeekend(build.finish_time, "UTC")`, want: true},
+		{filter: `now() > build.finish_time && now() - build.finish_time < duration("1h")`, want: true},
+	} {
+		t.Run(tc.filter, func(t *testing.T) {
+			p, err := MakeCELPredicate(tc.filter)
+			if err != nil {
+				t.Fatalf("MakeCELPredicate failed: %v", err)
+			}
+			got, err := p.eval(context.Background(), build)
+			if err != nil {
+				t.Fatalf("eval failed: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	// Type errors are caught when the filter is compiled.
+	for _, filter := range []string{
+		`hasTag(build)`,
+		`hasTag(build, 1)`,
+		`substitution("_ENV")`,
+		`failedSteps(build).size()`,
+		`duration(build.steps[0]) > duration("1m")`,
+		`timeOfDay(build.finish_time) == "18:00"`,
+	} {
+		if _, err := MakeCELPredicate(filter); err == nil {
+			t.Errorf("MakeCELPredicate(%q) succeeded, want error", filter)
+		}
+	}
+
+	// Bad arguments are caught when the filter is evaluated.
+	for _, filter := range []string{
+		`timeOfDay(build.finish_time, "Not/AZone") == ""`,
+		`build.id.find("[") == ""`,
+	} {
+		p, err := MakeCELPredicate(filter)
+		if err != nil {
+			t.Fatalf("MakeCELPredicate(%q) failed: %v", filter, err)
+		}
+		if _, err := p.eval(context.Background(), build); err == nil {
+			t.Errorf("eval of %q succeeded, want error", filter)
+		}
+	}
+}
+
+func TestBuildDuration(t *testing.T) {
+	start := time.Date(2026, 1, 3, 17, 30, 0, 0, time.UTC)
+	oldNow := celNow
+	celNow = func() time.Time { return start.Add(5 * time.Minute) }
+	t.Cleanup(func() { celNow = oldNow })
+
+	for _, tc := range []struct {
+		name  string
+		build *cbpb.Build
+		want  time.Duration
+	}{
+		{name: "not started", build: &cbpb.Build{}, want: 0},
+		{name: "running", build: &cbpb.Build{StartTime: timestamppb.New(start)}, want: 5 * time.Minute},
+		{name: "finished", build: &cbpb.Build{StartTime: timestamppb.New(start), FinishTime: timestamppb.New(start.Add(time.Minute))}, want: time.Minute},
+	} {
+		if got := buildDuration(tc.build); got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
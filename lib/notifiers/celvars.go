@@ -0,0 +1,119 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifiers
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	cbpb "cloud.google.com/go/cloudbuild/apiv1/v2/cloudbuildpb"
+)
+
+// celVars returns the variables that the CEL programs of filters and `cel(...)` params are evaluated with.
+func celVars(ctx context.Context, build *cbpb.Build, env map[string]string) map[string]interface{} {
+	vars := map[string]interface{}{
+		"build":           build,
+		"previous_status": int64(cbpb.Build_STATUS_UNKNOWN),
+		"transition":      "",
+		"params":          celParams(ctx),
+		"env":             env,
+		"message":         celMessage(EventMetadataFromContext(ctx)),
+	}
+	if t := TransitionFromContext(ctx); t != nil {
+		vars["previous_status"], vars["transition"] = int64(t.PreviousStatus), t.Kind
+	}
+	return vars
+}
+
+type filterParamsKey struct{}
+
+// withFilterParams returns a context whose CEL filters see the given resolved params as `params`.
+func withFilterParams(ctx context.Context, params map[string]string) context.Context {
+	return context.WithValue(ctx, filterParamsKey{}, params)
+}
+
+// celParams returns the resolved params that CEL filters see as `params`, which is empty outside of a route with
+// params.
+func celParams(ctx context.Context) map[string]string {
+	if params, _ := ctx.Value(filterParamsKey{}).(map[string]string); params != nil {
+		return params
+	}
+	return map[string]string{}
+}
+
+// celMessage returns the metadata of the event that delivered the Build as CEL filters see it as `message`.
+func celMessage(em *EventMetadata) map[string]interface{} {
+	if em == nil {
+		em = new(EventMetadata)
+	}
+	attrs := em.Attributes
+	if attrs == nil {
+		attrs = map[string]string{}
+	}
+	return map[string]interface{}{
+		"id":         em.ID,
+		"source":     em.Source,
+		"type":       em.Type,
+		"attributes": attrs,
+	}
+}
+
+// celEnvPrefix is the prefix of the environment variables that CEL programs can see.
+const celEnvPrefix = "NOTIFIER_"
+
+// celEnv returns the environment variables as CEL filters (and `cel(...)` params) see them as `env`. Only the
+// deployment-specific variables whose name starts with celEnvPrefix (e.g. NOTIFIER_DEPLOY_ENV) are included, with the
+// prefix: the rest of the environment holds credentials such as VAULT_TOKEN and the secrets behind `env://`
+// references, which `cel(...)` params would otherwise copy into notifications.
+func celEnv() map[string]string {
+	env := map[string]string{}
+	for _, kv := range os.Environ() {
+		if k, v, ok := strings.Cut(kv, "="); ok && strings.HasPrefix(k, celEnvPrefix) {
+			env[k] = v
+		}
+	}
+	return env
+}
+
+// paramsNotifier resolves the params of a route for every Build so that the filters of the route's notifier (and of
+// its rate limit and schedule) can refer to them as `params`.
+type paramsNotifier struct {
+	notifier Notifier
+	br       BindingResolver
+	sg       SecretGetter
+}
+
+// SetUp is a no-op since the wrapped notifier is set up by setUpNotifier.
+func (p *paramsNotifier) SetUp(_ context.Context, _ *Config, _ string, _ SecretGetter, _ BindingResolver) error {
+	return nil
+}
+
+func (p *paramsNotifier) SendNotification(ctx context.Context, build *cbpb.Build) error {
+	params, err := p.br.Resolve(ctx, p.sg, build)
+	if err != nil {
+		// Filters that refer to the params fail to evaluate and thus do not match, but others still can.
+		Warningf(ctx, "failed to resolve params for the filter of Build %q: %v", build.GetId(), err)
+	}
+	return p.notifier.SendNotification(withFilterParams(ctx, params), build)
+}
+
+// CheckHealth checks the wrapped notifier if it implements HealthChecker.
+func (p *paramsNotifier) CheckHealth(ctx context.Context) error {
+	if hc, ok := p.notifier.(HealthChecker); ok {
+		return hc.CheckHealth(ctx)
+	}
+	return nil
+}
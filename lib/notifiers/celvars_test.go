@@ -0,0 +1,120 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifiers
+
+import (
+	"context"
+	"testing"
+
+	cbpb "cloud.google.com/go/cloudbuild/apiv1/v2/cloudbuildpb"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCELFilterVars(t *testing.T) {
+	t.Setenv("NOTIFIER_DEPLOY_ENV", "prod")
+	t.Setenv("DEPLOY_REGION", "us-central1")
+	t.Setenv("VAULT_TOKEN", "some-token")
+	build := &cbpb.Build{Id: "some-build-id", Substitutions: map[string]string{"BRANCH_NAME": "main"}}
+	ctx := withEventMetadata(withFilterParams(context.Background(), map[string]string{"watchBranch": "main"}),
+		&EventMetadata{ID: "message-id", Attributes: map[string]string{"status": "SUCCESS"}})
+
+	for _, tc := range []struct {
+		filter string
+		ctx    context.Context
+		want   bool
+	}{
+		{filter: `build.substitutions.BRANCH_NAME == params.watchBranch`, ctx: ctx, want: true},
+		{filter: `env.NOTIFIER_DEPLOY_ENV == "prod" && !has(env.NOTIFIER_NO_SUCH_VAR)`, ctx: ctx, want: true},
+		// Only the variables with the NOTIFIER_ prefix are visible.
+		{filter: `has(env.VAULT_TOKEN)`, ctx: ctx, want: false},
+		{filter: `has(env.DEPLOY_REGION) || has(env.NOTIFIER_DEPLOY_REGION)`, ctx: ctx, want: false},
+		{filter: `message.id == "message-id" && message.attributes.status == "SUCCESS"`, ctx: ctx, want: true},
+		{filter: `"watchBranch" in params`, ctx: context.Background(), want: false},
+		{filter: `message.id == "" && message.attributes.size() == 0`, ctx: context.Background(), want: true},
+	} {
+		t.Run(tc.filter, func(t *testing.T) {
+			p, err := MakeCELPredicate(tc.filter)
+			if err != nil {
+				t.Fatalf("MakeCELPredicate failed: %v", err)
+			}
+			got, err := p.eval(tc.ctx, build)
+			if err != nil {
+				t.Fatalf("eval failed: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	if _, err := MakeCELPredicate(`params.watchBranch == 1`); err == nil {
+		t.Error("MakeCELPredicate succeeded for a filter that compares a param with an int, want error")
+	}
+}
+
+// filteringNotifier records the IDs of the Builds that match its filter.
+type filteringNotifier struct {
+	filter EventFilter
+	sent   *[]string
+}
+
+func (f *filteringNotifier) SetUp(_ context.Context, cfg *Config, _ string, _ SecretGetter, _ BindingResolver) error {
+	p, err := MakeCELPredicate(cfg.Spec.Notification.Filter)
+	if err != nil {
+		return err
+	}
+	f.filter = p
+	return nil
+}
+
+func (f *filteringNotifier) SendNotification(ctx context.Context, build *cbpb.Build) error {
+	if f.filter.Apply(ctx, build) {
+		*f.sent = append(*f.sent, build.GetId())
+	}
+	return nil
+}
+
+func TestParamsNotifier(t *testing.T) {
+	cfg := &Config{
+		APIVersion: "cloud-build-notifiers/v1",
+		Spec: &Spec{Notification: &Notification{
+			Filter: "build.substitutions.BRANCH_NAME == params.watchBranch",
+			Params: map[string]string{"watchBranch": "$(build.substitutions._WATCH_BRANCH)"},
+		}},
+	}
+	var sent []string
+	n, err := setUpNotifier(context.Background(), &filteringNotifier{sent: &sent}, cfg, []string{""}, new(setupCheckSecretGetter), nil, nil)
+	if err != nil {
+		t.Fatalf("setUpNotifier failed: %v", err)
+	}
+
+	for _, b := range []*cbpb.Build{
+		{Id: "watched", Substitutions: map[string]string{"BRANCH_NAME": "main", "_WATCH_BRANCH": "main"}},
+		{Id: "other-branch", Substitutions: map[string]string{"BRANCH_NAME": "dev", "_WATCH_BRANCH": "main"}},
+		// The param fails to resolve, so the filter does not match.
+		{Id: "unresolved", Substitutions: map[string]string{"BRANCH_NAME": "main"}},
+	} {
+		if err := n.SendNotification(context.Background(), b); err != nil {
+			t.Fatalf("SendNotification failed: %v", err)
+		}
+	}
+	if diff := cmp.Diff([]string{"watched"}, sent); diff != "" {
+		t.Errorf("unexpected sent notifications diff: (want- got+)\n%s", diff)
+	}
+
+	if routes := routeNotifiers(n); len(routes) != 1 || routes[0] == n {
+		t.Errorf("routeNotifiers(%v) = %v, want the unwrapped notifier", n, routes)
+	}
+}
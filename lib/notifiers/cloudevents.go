@@ -0,0 +1,149 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifiers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// messagePublishedEventType is the CloudEvent type of Pub/Sub messages that are delivered through Eventarc.
+	messagePublishedEventType = "google.cloud.pubsub.topic.v1.messagePublished"
+	cloudEventsContentType    = "application/cloudevents+json"
+)
+
+// EventMetadata describes the event that delivered a Build: either a CloudEvent, or a Pub/Sub message (in which case
+// the metadata is filled in as if the message had been delivered as a CloudEvent through Eventarc).
+type EventMetadata struct {
+	ID     string    `json:"ID"`
+	Source string    `json:"Source"`
+	Type   string    `json:"Type"`
+	Time   time.Time `json:"Time"`
+
+	// Attributes are the attributes of the Pub/Sub message, e.g. `buildId` and `status`.
+	Attributes map[string]string `json:"Attributes,omitempty"`
+}
+
+type eventMetadataKey struct{}
+
+func withEventMetadata(ctx context.Context, em *EventMetadata) context.Context {
+	return context.WithValue(ctx, eventMetadataKey{}, em)
+}
+
+// EventMetadataFromContext returns the metadata of the event that delivered the Build that SendNotification was called
+// with, or nil if there is none.
+func EventMetadataFromContext(ctx context.Context) *EventMetadata {
+	em, _ := ctx.Value(eventMetadataKey{}).(*EventMetadata)
+	return em
+}
+
+// pubSubEventMetadata returns the EventMetadata for the given Pub/Sub message.
+func pubSubEventMetadata(subscription string, msg *pubSubPushMessage) *EventMetadata {
+	em := &EventMetadata{
+		ID:         msg.ID,
+		Source:     "//pubsub.googleapis.com/" + subscription,
+		Type:       messagePublishedEventType,
+		Attributes: msg.Attributes,
+	}
+	if t, err := time.Parse(time.RFC3339Nano, msg.PublishTime); err == nil {
+		em.Time = t
+	}
+	return em
+}
+
+// isCloudEvent returns true iff the given request holds a CloudEvent in either binary (`ce-*` headers) or structured
+// (`application/cloudevents+json` body) mode.
+func isCloudEvent(r *http.Request) bool {
+	return r.Header.Get("ce-specversion") != "" || strings.HasPrefix(r.Header.Get("Content-Type"), cloudEventsContentType)
+}
+
+// structuredCloudEvent is the JSON form of a CloudEvent in structured mode.
+type structuredCloudEvent struct {
+	SpecVersion string          `json:"specversion"`
+	ID          string          `json:"id"`
+	Source      string          `json:"source"`
+	Type        string          `json:"type"`
+	Time        string          `json:"time"`
+	Data        json.RawMessage `json:"data"`
+	DataBase64  []byte          `json:"data_base64"`
+}
+
+// messagePublishedData is the data of a `google.cloud.pubsub.topic.v1.messagePublished` CloudEvent.
+type messagePublishedData struct {
+	Message struct {
+		Data        []byte            `json:"data"`
+		Attributes  map[string]string `json:"attributes"`
+		MessageID   string            `json:"messageId"`
+		PublishTime string            `json:"publishTime"`
+	} `json:"message"`
+	Subscription string `json:"subscription"`
+}
+
+// decodeCloudEvent returns the Pub/Sub message (holding the Build) and the metadata of the CloudEvent in the given
+// request headers and body. The data of `messagePublished` events is unwrapped; the data of any other event type is
+// expected to be the Build itself.
+func decodeCloudEvent(h http.Header, body []byte) (*pubSubPushMessage, *EventMetadata, error) {
+	var ce structuredCloudEvent
+	if strings.HasPrefix(h.Get("Content-Type"), cloudEventsContentType) {
+		if err := json.Unmarshal(body, &ce); err != nil {
+			return nil, nil, fmt.Errorf("failed to unmarshal structured CloudEvent: %w", err)
+		}
+		if len(ce.Data) == 0 {
+			ce.Data = ce.DataBase64
+		}
+	} else {
+		ce = structuredCloudEvent{
+			SpecVersion: h.Get("ce-specversion"),
+			ID:          h.Get("ce-id"),
+			Source:      h.Get("ce-source"),
+			Type:        h.Get("ce-type"),
+			Time:        h.Get("ce-time"),
+			Data:        body,
+		}
+	}
+
+	if ce.SpecVersion == "" || ce.ID == "" || ce.Source == "" || ce.Type == "" {
+		return nil, nil, errors.New("expected CloudEvent to have the `specversion`, `id`, `source`, and `type` attributes")
+	}
+	em := &EventMetadata{ID: ce.ID, Source: ce.Source, Type: ce.Type}
+	if ce.Time != "" {
+		t, err := time.Parse(time.RFC3339Nano, ce.Time)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse CloudEvent time %q: %w", ce.Time, err)
+		}
+		em.Time = t
+	}
+
+	if ce.Type != messagePublishedEventType {
+		return &pubSubPushMessage{Data: ce.Data, ID: ce.ID, PublishTime: ce.Time}, em, nil
+	}
+
+	var mpd messagePublishedData
+	if err := json.Unmarshal(ce.Data, &mpd); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal %s CloudEvent data: %w", ce.Type, err)
+	}
+	msg := &pubSubPushMessage{Data: mpd.Message.Data, Attributes: mpd.Message.Attributes, ID: mpd.Message.MessageID, PublishTime: mpd.Message.PublishTime}
+	if msg.ID == "" {
+		msg.ID = ce.ID
+	}
+	em.Attributes = msg.Attributes
+	return msg, em, nil
+}
@@ -0,0 +1,210 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifiers
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	cbpb "cloud.google.com/go/cloudbuild/apiv1/v2/cloudbuildpb"
+	"github.com/google/go-cmp/cmp"
+)
+
+const (
+	ceBuildJSON = `{"id": "some-build-id", "status": "SUCCESS"}`
+	ceSource    = "//pubsub.googleapis.com/projects/p/topics/cloud-builds"
+	ceTime      = "2026-01-02T03:04:05Z"
+)
+
+var ceMessagePublished = fmt.Sprintf(`{"message": {"data": %q, "messageId": "message-id", "publishTime": %q}, "subscription": "projects/p/subscriptions/eventarc"}`,
+	base64.StdEncoding.EncodeToString([]byte(ceBuildJSON)), ceTime)
+
+func TestDecodeCloudEvent(t *testing.T) {
+	wantTime, err := time.Parse(time.RFC3339, ceTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binaryHeaders := func(ceType string) http.Header {
+		h := http.Header{}
+		h.Set("Content-Type", "application/json")
+		h.Set("ce-specversion", "1.0")
+		h.Set("ce-id", "event-id")
+		h.Set("ce-source", ceSource)
+		h.Set("ce-type", ceType)
+		h.Set("ce-time", ceTime)
+		return h
+	}
+	structuredHeaders := http.Header{"Content-Type": {"application/cloudevents+json; charset=UTF-8"}}
+
+	for _, tc := range []struct {
+		name    string
+		headers http.Header
+		body    string
+		wantMsg *pubSubPushMessage
+		wantEM  *EventMetadata
+		wantErr bool
+	}{{
+		name:    "binary messagePublished",
+		headers: binaryHeaders(messagePublishedEventType),
+		body:    ceMessagePublished,
+		wantMsg: &pubSubPushMessage{Data: []byte(ceBuildJSON), ID: "message-id", PublishTime: ceTime},
+		wantEM:  &EventMetadata{ID: "event-id", Source: ceSource, Type: messagePublishedEventType, Time: wantTime},
+	}, {
+		name:    "binary Build",
+		headers: binaryHeaders("com.example.build"),
+		body:    ceBuildJSON,
+		wantMsg: &pubSubPushMessage{Data: []byte(ceBuildJSON), ID: "event-id", PublishTime: ceTime},
+		wantEM:  &EventMetadata{ID: "event-id", Source: ceSource, Type: "com.example.build", Time: wantTime},
+	}, {
+		name:    "structured messagePublished",
+		headers: structuredHeaders,
+		body: fmt.Sprintf(`{"specversion": "1.0", "id": "event-id", "source": %q, "type": %q, "time": %q, "data": %s}`,
+			ceSource, messagePublishedEventType, ceTime, ceMessagePublished),
+		wantMsg: &pubSubPushMessage{Data: []byte(ceBuildJSON), ID: "message-id", PublishTime: ceTime},
+		wantEM:  &EventMetadata{ID: "event-id", Source: ceSource, Type: messagePublishedEventType, Time: wantTime},
+	}, {
+		name:    "structured base64 Build",
+		headers: structuredHeaders,
+		body: fmt.Sprintf(`{"specversion": "1.0", "id": "event-id", "source": %q, "type": "com.example.build", "data_base64": %q}`,
+			ceSource, base64.StdEncoding.EncodeToString([]byte(ceBuildJSON))),
+		wantMsg: &pubSubPushMessage{Data: []byte(ceBuildJSON), ID: "event-id"},
+		wantEM:  &EventMetadata{ID: "event-id", Source: ceSource, Type: "com.example.build"},
+	}, {
+		name:    "missing attributes",
+		headers: structuredHeaders,
+		body:    fmt.Sprintf(`{"specversion": "1.0", "id": "event-id", "data": %s}`, ceBuildJSON),
+		wantErr: true,
+	}, {
+		name: "bad time",
+		headers: func() http.Header {
+			h := binaryHeaders("com.example.build")
+			h.Set("ce-time", "yesterday")
+			return h
+		}(),
+		body:    ceBuildJSON,
+		wantErr: true,
+	}, {
+		name:    "bad messagePublished data",
+		headers: binaryHeaders(messagePublishedEventType),
+		body:    `{"message": "nope"}`,
+		wantErr: true,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			msg, em, err := decodeCloudEvent(tc.headers, []byte(tc.body))
+			if err != nil {
+				if tc.wantErr {
+					t.Logf("got expected error: %v", err)
+					return
+				}
+				t.Fatalf("decodeCloudEvent got unexpected error: %v", err)
+			}
+			if tc.wantErr {
+				t.Fatal("decodeCloudEvent unexpectedly succeeded")
+			}
+			if diff := cmp.Diff(tc.wantMsg, msg); diff != "" {
+				t.Errorf("unexpected message diff: (want- got+)\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantEM, em); diff != "" {
+				t.Errorf("unexpected event metadata diff: (want- got+)\n%s", diff)
+			}
+		})
+	}
+}
+
+// eventRecordingNotifier records the Build ID and event metadata of the last notification.
+type eventRecordingNotifier struct {
+	buildID string
+	em      *EventMetadata
+}
+
+func (e *eventRecordingNotifier) SetUp(_ context.Context, _ *Config, _ string, _ SecretGetter, _ BindingResolver) error {
+	return nil
+}
+
+func (e *eventRecordingNotifier) SendNotification(ctx context.Context, build *cbpb.Build) error {
+	e.buildID = build.GetId()
+	e.em = EventMetadataFromContext(ctx)
+	return nil
+}
+
+func TestReceiverCloudEvents(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		headers    map[string]string
+		body       string
+		wantStatus int
+		wantEM     *EventMetadata
+	}{{
+		name: "binary CloudEvent",
+		headers: map[string]string{
+			"Content-Type":   "application/json",
+			"ce-specversion": "1.0",
+			"ce-id":          "event-id",
+			"ce-source":      ceSource,
+			"ce-type":        messagePublishedEventType,
+		},
+		body:       ceMessagePublished,
+		wantStatus: http.StatusOK,
+		wantEM:     &EventMetadata{ID: "event-id", Source: ceSource, Type: messagePublishedEventType},
+	}, {
+		name:    "Pub/Sub push message",
+		headers: map[string]string{"Content-Type": "application/json"},
+		body: fmt.Sprintf(`{"message": {"data": %q, "attributes": {"status": "SUCCESS"}, "id": "message-id", "publishTime": %q}, "subscription": "projects/p/subscriptions/eventarc"}`,
+			base64.StdEncoding.EncodeToString([]byte(ceBuildJSON)), ceTime),
+		wantStatus: http.StatusOK,
+		wantEM: &EventMetadata{
+			ID:         "message-id",
+			Source:     "//pubsub.googleapis.com/projects/p/subscriptions/eventarc",
+			Type:       messagePublishedEventType,
+			Time:       time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+			Attributes: map[string]string{"status": "SUCCESS"},
+		},
+	}, {
+		name:       "bad CloudEvent",
+		headers:    map[string]string{"Content-Type": "application/cloudevents+json"},
+		body:       `{"specversion": "1.0"}`,
+		wantStatus: http.StatusBadRequest,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			n := new(eventRecordingNotifier)
+			req := httptest.NewRequest(http.MethodPost, "http://notifer.example.com/", strings.NewReader(tc.body))
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+
+			newReceiver(n, &receiverParams{})(w, req)
+			if s := w.Result().StatusCode; s != tc.wantStatus {
+				t.Fatalf("result.StatusCode = %d, expected %d", s, tc.wantStatus)
+			}
+			if tc.wantStatus != http.StatusOK {
+				return
+			}
+
+			if n.buildID != "some-build-id" {
+				t.Errorf("got Build ID %q, want %q", n.buildID, "some-build-id")
+			}
+			if diff := cmp.Diff(tc.wantEM, n.em); diff != "" {
+				t.Errorf("unexpected event metadata diff: (want- got+)\n%s", diff)
+			}
+		})
+	}
+}
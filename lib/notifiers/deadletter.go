@@ -0,0 +1,153 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifiers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DeadLetterConfig is the data container for configuring where Pub/Sub messages go once all attempts at sending their
+// notification have failed.
+type DeadLetterConfig struct {
+	// URI is one of `gs://bucket/prefix` (one object per message), `file:///path/to/file` (one JSON line per message)
+	// or `log` (one error log line per message).
+	URI string `yaml:"uri"`
+}
+
+// deadLetter is the record that is written for a Pub/Sub message whose notification could not be sent.
+type deadLetter struct {
+	MessageID string          `json:"messageId"`
+	Envelope  json.RawMessage `json:"envelope"`
+	Error     string          `json:"error"`
+	Attempts  int             `json:"attempts"`
+	Time      time.Time       `json:"time"`
+}
+
+// deadLetterSink stores dead letters so that the receiver can ack their Pub/Sub messages.
+type deadLetterSink interface {
+	Write(context.Context, *deadLetter) error
+}
+
+type gcsWriterFactory interface {
+	NewWriter(ctx context.Context, bucket, object string) (io.WriteCloser, error)
+}
+
+type actualGCSWriterFactory struct {
+	client *lazyGCSClient
+}
+
+func (a *actualGCSWriterFactory) NewWriter(ctx context.Context, bucket, object string) (io.WriteCloser, error) {
+	c, err := a.client.get()
+	if err != nil {
+		return nil, err
+	}
+	return c.Bucket(bucket).Object(object).NewWriter(ctx), nil
+}
+
+// newDeadLetterSink returns the deadLetterSink for the given config's URI.
+func newDeadLetterSink(cfg *DeadLetterConfig, gwf gcsWriterFactory) (deadLetterSink, error) {
+	switch {
+	case cfg.URI == "log":
+		return new(logDeadLetterSink), nil
+	case strings.HasPrefix(cfg.URI, "gs://"):
+		split := strings.SplitN(strings.TrimPrefix(cfg.URI, "gs://"), "/", 2)
+		if len(split) != 2 || split[0] == "" || split[1] == "" {
+			return nil, fmt.Errorf("dead-letter URI has incorrect format (expected form: `gs://bucket/prefix`): %q", cfg.URI)
+		}
+		return &gcsDeadLetterSink{gwf: gwf, bucket: split[0], prefix: strings.TrimSuffix(split[1], "/")}, nil
+	case strings.HasPrefix(cfg.URI, "file://"):
+		path := strings.TrimPrefix(cfg.URI, "file://")
+		if path == "" {
+			return nil, fmt.Errorf("dead-letter URI has incorrect format (expected form: `file:///path/to/file`): %q", cfg.URI)
+		}
+		return &fileDeadLetterSink{path: path}, nil
+	default:
+		return nil, fmt.Errorf("expected dead-letter URI %q to be `log` or to start with `gs://` or `file://`", cfg.URI)
+	}
+}
+
+// gcsDeadLetterSink writes every dead letter to its own GCS object under a common prefix.
+type gcsDeadLetterSink struct {
+	gwf    gcsWriterFactory
+	bucket string
+	prefix string
+}
+
+func (g *gcsDeadLetterSink) Write(ctx context.Context, dl *deadLetter) error {
+	j, err := json.Marshal(dl)
+	if err != nil {
+		return fmt.Errorf("failed to encode dead letter: %w", err)
+	}
+
+	object := fmt.Sprintf("%s/%s-%s.json", g.prefix, dl.Time.UTC().Format("20060102T150405.000000000Z"), dl.MessageID)
+	w, err := g.gwf.NewWriter(ctx, g.bucket, object)
+	if err != nil {
+		return fmt.Errorf("failed to get writer for (bucket=%q, object=%q): %w", g.bucket, object, err)
+	}
+	if _, err := w.Write(j); err != nil {
+		w.Close()
+		return fmt.Errorf("failed to write dead letter to (bucket=%q, object=%q): %w", g.bucket, object, err)
+	}
+	// GCS writes are only committed on Close.
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to write dead letter to (bucket=%q, object=%q): %w", g.bucket, object, err)
+	}
+	return nil
+}
+
+// fileDeadLetterSink appends every dead letter as a JSON line to a local file.
+type fileDeadLetterSink struct {
+	mtx  sync.Mutex
+	path string
+}
+
+func (f *fileDeadLetterSink) Write(_ context.Context, dl *deadLetter) error {
+	j, err := json.Marshal(dl)
+	if err != nil {
+		return fmt.Errorf("failed to encode dead letter: %w", err)
+	}
+
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	fd, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open dead-letter file %q: %w", f.path, err)
+	}
+	if _, err := fd.Write(append(j, '\n')); err != nil {
+		fd.Close()
+		return fmt.Errorf("failed to write to dead-letter file %q: %w", f.path, err)
+	}
+	return fd.Close()
+}
+
+// logDeadLetterSink logs every dead letter as an error.
+type logDeadLetterSink struct{}
+
+func (l *logDeadLetterSink) Write(ctx context.Context, dl *deadLetter) error {
+	j, err := json.Marshal(dl)
+	if err != nil {
+		return fmt.Errorf("failed to encode dead letter: %w", err)
+	}
+	Errorf(ctx, "dead letter: %s", j)
+	return nil
+}
@@ -0,0 +1,156 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifiers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type fakeGCSWriter struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (f *fakeGCSWriter) Close() error {
+	f.closed = true
+	return nil
+}
+
+type fakeGCSWriterFactory struct {
+	// A mapping of "gs://"+bucket+"/"+object -> writer.
+	writers map[string]*fakeGCSWriter
+}
+
+func (f *fakeGCSWriterFactory) NewWriter(_ context.Context, bucket, object string) (io.WriteCloser, error) {
+	w := new(fakeGCSWriter)
+	f.writers["gs://"+bucket+"/"+object] = w
+	return w, nil
+}
+
+var testDeadLetter = &deadLetter{
+	MessageID: "some-message-id",
+	Envelope:  json.RawMessage(`{"message":{"id":"some-message-id"}}`),
+	Error:     "failed to reticulate splines",
+	Attempts:  3,
+	Time:      time.Date(2020, 7, 1, 12, 0, 0, 0, time.UTC),
+}
+
+func TestNewDeadLetterSink(t *testing.T) {
+	for _, tc := range []struct {
+		uri     string
+		want    string // The formatted sink, minus any GCS writer factory.
+		wantErr bool
+	}{
+		{uri: "log", want: "*notifiers.logDeadLetterSink"},
+		{uri: "gs://bucket/some/prefix/", want: "gcs bucket=bucket prefix=some/prefix"},
+		{uri: "file:///tmp/dead-letters.jsonl", want: "file path=/tmp/dead-letters.jsonl"},
+		{uri: "gs://bucket-without-prefix", wantErr: true},
+		{uri: "file://", wantErr: true},
+		{uri: "https://example.com/dead-letters", wantErr: true},
+	} {
+		t.Run(tc.uri, func(t *testing.T) {
+			got, err := newDeadLetterSink(&DeadLetterConfig{URI: tc.uri}, nil)
+			if err != nil {
+				if tc.wantErr {
+					t.Logf("got expected error: %v", err)
+					return
+				}
+				t.Fatalf("newDeadLetterSink(%q) failed unexpectedly: %v", tc.uri, err)
+			}
+			if tc.wantErr {
+				t.Fatalf("newDeadLetterSink(%q) succeeded unexpectedly", tc.uri)
+			}
+
+			var desc string
+			switch s := got.(type) {
+			case *gcsDeadLetterSink:
+				desc = fmt.Sprintf("gcs bucket=%s prefix=%s", s.bucket, s.prefix)
+			case *fileDeadLetterSink:
+				desc = fmt.Sprintf("file path=%s", s.path)
+			default:
+				desc = fmt.Sprintf("%T", s)
+			}
+			if desc != tc.want {
+				t.Errorf("newDeadLetterSink(%q) = %s, want %s", tc.uri, desc, tc.want)
+			}
+		})
+	}
+}
+
+func TestGCSDeadLetterSink(t *testing.T) {
+	f := &fakeGCSWriterFactory{writers: map[string]*fakeGCSWriter{}}
+	s := &gcsDeadLetterSink{gwf: f, bucket: "bucket", prefix: "dead"}
+
+	if err := s.Write(context.Background(), testDeadLetter); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	const wantObject = "gs://bucket/dead/20200701T120000.000000000Z-some-message-id.json"
+	w, ok := f.writers[wantObject]
+	if !ok {
+		t.Fatalf("expected a write to %q, got writes: %v", wantObject, f.writers)
+	}
+	if !w.closed {
+		t.Error("expected the GCS writer to be closed")
+	}
+
+	got := new(deadLetter)
+	if err := json.Unmarshal(w.Bytes(), got); err != nil {
+		t.Fatalf("failed to decode written dead letter: %v", err)
+	}
+	if diff := cmp.Diff(testDeadLetter, got); diff != "" {
+		t.Errorf("unexpected dead letter diff: (want- got+)\n%s", diff)
+	}
+}
+
+func TestFileDeadLetterSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dead-letters.jsonl")
+	s := &fileDeadLetterSink{path: path}
+
+	for i := 0; i < 2; i++ {
+		if err := s.Write(context.Background(), testDeadLetter); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 dead-letter lines, got %d:\n%s", len(lines), b)
+	}
+	for _, l := range lines {
+		got := new(deadLetter)
+		if err := json.Unmarshal([]byte(l), got); err != nil {
+			t.Fatalf("failed to decode written dead letter: %v", err)
+		}
+		if diff := cmp.Diff(testDeadLetter, got); diff != "" {
+			t.Errorf("unexpected dead letter diff: (want- got+)\n%s", diff)
+		}
+	}
+}
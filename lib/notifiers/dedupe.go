@@ -0,0 +1,257 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifiers
+
+import (
+	"bufio"
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	cbpb "cloud.google.com/go/cloudbuild/apiv1/v2/cloudbuildpb"
+)
+
+const defaultDedupeSize = 10000
+
+// DedupeConfig is the data container for configuring how redelivered Pub/Sub messages are detected.
+type DedupeConfig struct {
+	// Type is either `memory` (the default) or `file`.
+	Type string `yaml:"type"`
+	// Size is the maximum number of delivery keys that are remembered. Defaults to 10000.
+	Size int `yaml:"size"`
+	// Path is the local file that delivery keys are persisted to when Type is `file`.
+	Path string `yaml:"path"`
+}
+
+// dedupeStore remembers the keys of deliveries that have already completed.
+type dedupeStore interface {
+	// Contains returns true iff the given key was previously added.
+	Contains(ctx context.Context, key string) (bool, error)
+	// Add records the given key.
+	Add(ctx context.Context, key string) error
+}
+
+// dedupeKeys returns the keys that identify the delivery of the given Pub/Sub message and Build:
+// one for the message ID and one for the (build ID, status) pair.
+func dedupeKeys(messageID string, build *cbpb.Build) []string {
+	var keys []string
+	if messageID != "" {
+		keys = append(keys, "message/"+messageID)
+	}
+	if build.GetId() != "" {
+		keys = append(keys, fmt.Sprintf("build/%s/%s", build.GetId(), build.GetStatus()))
+	}
+	return keys
+}
+
+// containsAny returns the first of the given keys that the store contains, if any.
+// Errors from the store are logged and otherwise ignored, so that a broken store never blocks notifications.
+func containsAny(ctx context.Context, s dedupeStore, keys []string) (string, bool) {
+	for _, k := range keys {
+		ok, err := s.Contains(ctx, k)
+		if err != nil {
+			Warningf(ctx, "failed to look up dedupe key %q: %v", k, err)
+			continue
+		}
+		if ok {
+			return k, true
+		}
+	}
+	return "", false
+}
+
+// addAll adds all of the given keys to the store, logging any errors.
+func addAll(ctx context.Context, s dedupeStore, keys []string) {
+	for _, k := range keys {
+		if err := s.Add(ctx, k); err != nil {
+			Warningf(ctx, "failed to add dedupe key %q: %v", k, err)
+		}
+	}
+}
+
+func validateDedupeConfig(cfg *DedupeConfig) error {
+	if cfg.Size < 0 {
+		return fmt.Errorf("expected dedupe size to be positive, got %d", cfg.Size)
+	}
+	switch cfg.Type {
+	case "", "memory":
+	case "file":
+		if cfg.Path == "" {
+			return errors.New("expected dedupe path to be present for the `file` type")
+		}
+	default:
+		return fmt.Errorf("expected dedupe type %q to be one of `memory` or `file`", cfg.Type)
+	}
+	return nil
+}
+
+// newDedupeStore returns the dedupeStore for the given config.
+func newDedupeStore(cfg *DedupeConfig) (dedupeStore, error) {
+	if err := validateDedupeConfig(cfg); err != nil {
+		return nil, err
+	}
+
+	size := cfg.Size
+	if size == 0 {
+		size = defaultDedupeSize
+	}
+	if cfg.Type == "file" {
+		return newFileDedupeStore(cfg.Path, size)
+	}
+	return newLRUDedupeStore(size), nil
+}
+
+// lruDedupeStore is an in-memory dedupeStore that forgets the least recently used keys once it is full.
+type lruDedupeStore struct {
+	mtx   sync.Mutex
+	size  int
+	order *list.List               // Most recently used keys are at the front.
+	keys  map[string]*list.Element // Key => its element in order.
+}
+
+func newLRUDedupeStore(size int) *lruDedupeStore {
+	return &lruDedupeStore{
+		size:  size,
+		order: list.New(),
+		keys:  map[string]*list.Element{},
+	}
+}
+
+func (l *lruDedupeStore) Contains(_ context.Context, key string) (bool, error) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	e, ok := l.keys[key]
+	if ok {
+		l.order.MoveToFront(e)
+	}
+	return ok, nil
+}
+
+func (l *lruDedupeStore) Add(_ context.Context, key string) error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	l.add(key)
+	return nil
+}
+
+func (l *lruDedupeStore) add(key string) {
+	if e, ok := l.keys[key]; ok {
+		l.order.MoveToFront(e)
+		return
+	}
+
+	l.keys[key] = l.order.PushFront(key)
+	for l.order.Len() > l.size {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.keys, oldest.Value.(string))
+	}
+}
+
+// list returns the keys from least to most recently used.
+func (l *lruDedupeStore) list() []string {
+	keys := make([]string, 0, l.order.Len())
+	for e := l.order.Back(); e != nil; e = e.Prev() {
+		keys = append(keys, e.Value.(string))
+	}
+	return keys
+}
+
+// fileDedupeStore is an lruDedupeStore that appends its keys to a local file, so that they survive restarts.
+// The file is compacted to the keys that are still remembered once it grows to twice the store's size.
+type fileDedupeStore struct {
+	*lruDedupeStore
+	path    string
+	written int // Number of keys in the file.
+}
+
+func newFileDedupeStore(path string, size int) (*fileDedupeStore, error) {
+	f := &fileDedupeStore{lruDedupeStore: newLRUDedupeStore(size), path: path}
+
+	fd, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dedupe file %q: %w", path, err)
+	}
+	defer fd.Close()
+
+	s := bufio.NewScanner(fd)
+	for s.Scan() {
+		if key := s.Text(); key != "" {
+			f.add(key)
+			f.written++
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read dedupe file %q: %w", path, err)
+	}
+	return f, nil
+}
+
+func (f *fileDedupeStore) Add(_ context.Context, key string) error {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	f.add(key)
+	if f.written+1 > 2*f.size {
+		return f.compact()
+	}
+
+	fd, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open dedupe file %q: %w", f.path, err)
+	}
+	if _, err := fmt.Fprintln(fd, key); err != nil {
+		fd.Close()
+		return fmt.Errorf("failed to write to dedupe file %q: %w", f.path, err)
+	}
+	f.written++
+	return fd.Close()
+}
+
+// compact rewrites the file with only the remembered keys. The caller must hold the lock.
+func (f *fileDedupeStore) compact() error {
+	tmp := f.path + ".tmp"
+	fd, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to create dedupe file %q: %w", tmp, err)
+	}
+
+	w := bufio.NewWriter(fd)
+	keys := f.list()
+	for _, k := range keys {
+		fmt.Fprintln(w, k)
+	}
+	if err := w.Flush(); err != nil {
+		fd.Close()
+		return fmt.Errorf("failed to write dedupe file %q: %w", tmp, err)
+	}
+	if err := fd.Close(); err != nil {
+		return fmt.Errorf("failed to write dedupe file %q: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, f.path); err != nil {
+		return fmt.Errorf("failed to replace dedupe file %q: %w", f.path, err)
+	}
+
+	f.written = len(keys)
+	return nil
+}
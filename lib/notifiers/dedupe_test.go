@@ -0,0 +1,130 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifiers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	cbpb "cloud.google.com/go/cloudbuild/apiv1/v2/cloudbuildpb"
+	"github.com/google/go-cmp/cmp"
+)
+
+func mustContain(t *testing.T, s dedupeStore, key string, want bool) {
+	t.Helper()
+	got, err := s.Contains(context.Background(), key)
+	if err != nil {
+		t.Fatalf("Contains(%q) failed: %v", key, err)
+	}
+	if got != want {
+		t.Errorf("Contains(%q) = %v, want %v", key, got, want)
+	}
+}
+
+func TestDedupeKeys(t *testing.T) {
+	got := dedupeKeys("msg-1", &cbpb.Build{Id: "build-1", Status: cbpb.Build_FAILURE})
+	want := []string{"message/msg-1", "build/build-1/FAILURE"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected dedupe keys diff: (want- got+)\n%s", diff)
+	}
+
+	if got := dedupeKeys("", new(cbpb.Build)); len(got) != 0 {
+		t.Errorf("dedupeKeys of an empty message and Build = %v, want none", got)
+	}
+}
+
+func TestLRUDedupeStore(t *testing.T) {
+	ctx := context.Background()
+	s := newLRUDedupeStore(2)
+
+	s.Add(ctx, "a")
+	s.Add(ctx, "b")
+	mustContain(t, s, "a", true) // "a" is now the most recently used.
+	s.Add(ctx, "c")              // Evicts "b".
+
+	mustContain(t, s, "a", true)
+	mustContain(t, s, "b", false)
+	mustContain(t, s, "c", true)
+}
+
+func TestFileDedupeStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "dedupe")
+
+	s, err := newFileDedupeStore(path, 2)
+	if err != nil {
+		t.Fatalf("newFileDedupeStore failed: %v", err)
+	}
+	for _, k := range []string{"a", "b", "c", "d", "e"} {
+		if err := s.Add(ctx, k); err != nil {
+			t.Fatalf("Add(%q) failed: %v", k, err)
+		}
+	}
+
+	// Adding "e" went over twice the size and compacted the file.
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Fields(string(b)); !cmp.Equal(got, []string{"d", "e"}) {
+		t.Errorf("got compacted dedupe file keys %v, want [d e]", got)
+	}
+
+	reopened, err := newFileDedupeStore(path, 2)
+	if err != nil {
+		t.Fatalf("newFileDedupeStore failed on reopen: %v", err)
+	}
+	mustContain(t, reopened, "c", false)
+	mustContain(t, reopened, "d", true)
+	mustContain(t, reopened, "e", true)
+}
+
+func TestNewDedupeStoreErrors(t *testing.T) {
+	for _, cfg := range []*DedupeConfig{
+		{Type: "redis"},
+		{Type: "file"},
+		{Size: -1},
+	} {
+		if _, err := newDedupeStore(cfg); err == nil {
+			t.Errorf("newDedupeStore(%+v) succeeded unexpectedly", cfg)
+		}
+	}
+}
+
+func TestReceiverSkipsDuplicates(t *testing.T) {
+	bc := make(chan *cbpb.Build, 3)
+	handler := newReceiver(&fakeNotifier{notifs: bc}, &receiverParams{dedupe: newLRUDedupeStore(10)})
+
+	for _, b := range []*cbpb.Build{
+		{Id: "build-1", Status: cbpb.Build_WORKING},
+		{Id: "build-1", Status: cbpb.Build_WORKING}, // Same message ID: skipped.
+	} {
+		req := httptest.NewRequest(http.MethodPost, "http://notifer.example.com/", buildToBuffer(t, b))
+		w := httptest.NewRecorder()
+		handler(w, req)
+		if s := w.Result().StatusCode; s != http.StatusOK {
+			t.Errorf("result.StatusCode = %d, expected %d", s, http.StatusOK)
+		}
+	}
+
+	if got := len(bc); got != 1 {
+		t.Errorf("notifier was sent %d Builds, want 1", got)
+	}
+}
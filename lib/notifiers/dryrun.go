@@ -0,0 +1,183 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifiers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// capturedDelivery is the record of a delivery that was captured instead of made in dry-run mode.
+type capturedDelivery struct {
+	// Kind is the delivery mechanism, e.g. `http`, `smtp`, or `bigquery`.
+	Kind string `json:"kind"`
+	// Destination is where the delivery would have gone. For HTTP requests, it is only the method and host, since
+	// webhook URLs are often secrets.
+	Destination string            `json:"destination"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Payload     string            `json:"payload"`
+	Time        time.Time         `json:"time"`
+}
+
+// deliverySink stores the captured deliveries of dry-run mode.
+type deliverySink interface {
+	Capture(context.Context, *capturedDelivery) error
+}
+
+// dryRun is where deliveries are captured in dry-run mode. It is nil unless Main is started with DRY_RUN.
+var dryRun deliverySink
+
+// newDeliverySink returns the deliverySink for the given DRY_RUN value, which is either `log` (or a true boolean, as
+// in strconv.ParseBool) or `file:///path/to/file` (one JSON line per delivery). It returns nil if the value is empty or
+// a false boolean, which leaves dry-run mode off.
+func newDeliverySink(v string) (deliverySink, error) {
+	if v == "" {
+		return nil, nil
+	}
+	if on, err := strconv.ParseBool(v); err == nil {
+		if !on {
+			return nil, nil
+		}
+		return new(logDeliverySink), nil
+	}
+	switch {
+	case v == "log":
+		return new(logDeliverySink), nil
+	case strings.HasPrefix(v, "file://") && v != "file://":
+		return &fileDeliverySink{path: strings.TrimPrefix(v, "file://")}, nil
+	default:
+		return nil, fmt.Errorf("expected DRY_RUN %q to be `log`, a boolean, or `file:///path/to/file`", v)
+	}
+}
+
+// DryRun returns true iff the notifier runs in dry-run mode (see the DRY_RUN environment variable), in which
+// notifiers must not deliver anything. Notifiers that deliver over HTTP can use DeliveryClient instead; others
+// should pass what they would have delivered to CaptureDelivery.
+func DryRun() bool {
+	return dryRun != nil
+}
+
+// DeliveryClient returns the HTTP client that notifiers should make their deliveries with. In dry-run mode, it
+// captures every request and answers it with an empty `200 OK` instead of sending it.
+func DeliveryClient() *http.Client {
+	if dryRun == nil {
+		return http.DefaultClient
+	}
+	return &http.Client{Transport: &capturingTransport{sink: dryRun}}
+}
+
+// CaptureDelivery records a delivery that was not made since the notifier runs in dry-run mode. kind names the
+// delivery mechanism (e.g. `smtp`) and destination says where the payload would have gone.
+func CaptureDelivery(ctx context.Context, kind, destination string, payload []byte) error {
+	if dryRun == nil {
+		return fmt.Errorf("expected the notifier to run in dry-run mode to capture a %s delivery", kind)
+	}
+	return dryRun.Capture(ctx, &capturedDelivery{Kind: kind, Destination: destination, Payload: string(payload), Time: time.Now()})
+}
+
+// capturingTransport is an http.RoundTripper that captures requests instead of sending them.
+type capturingTransport struct {
+	sink deliverySink
+}
+
+// redactedHeaders are the request headers whose values are never captured.
+var redactedHeaders = map[string]bool{"Authorization": true, "Cookie": true}
+
+func (c *capturingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var payload []byte
+	if req.Body != nil {
+		b, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+		payload = b
+	}
+
+	cd := &capturedDelivery{
+		Kind:        "http",
+		Destination: fmt.Sprintf("%s %s://%s", req.Method, req.URL.Scheme, req.URL.Host),
+		Headers:     map[string]string{},
+		Payload:     string(payload),
+		Time:        time.Now(),
+	}
+	for k := range req.Header {
+		cd.Headers[k] = req.Header.Get(k)
+		if redactedHeaders[k] {
+			cd.Headers[k] = "[REDACTED]"
+		}
+	}
+	if err := c.sink.Capture(req.Context(), cd); err != nil {
+		return nil, err
+	}
+
+	return &http.Response{
+		Status:     "200 OK",
+		StatusCode: http.StatusOK,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{},
+		Body:       io.NopCloser(bytes.NewReader(nil)),
+		Request:    req,
+	}, nil
+}
+
+// logDeliverySink logs every captured delivery.
+type logDeliverySink struct{}
+
+func (l *logDeliverySink) Capture(ctx context.Context, cd *capturedDelivery) error {
+	j, err := json.Marshal(cd)
+	if err != nil {
+		return fmt.Errorf("failed to encode captured delivery: %w", err)
+	}
+	Infof(ctx, "dry run: captured delivery: %s", j)
+	return nil
+}
+
+// fileDeliverySink appends every captured delivery as a JSON line to a local file.
+type fileDeliverySink struct {
+	mtx  sync.Mutex
+	path string
+}
+
+func (f *fileDeliverySink) Capture(_ context.Context, cd *capturedDelivery) error {
+	j, err := json.Marshal(cd)
+	if err != nil {
+		return fmt.Errorf("failed to encode captured delivery: %w", err)
+	}
+
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	fd, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open dry-run file %q: %w", f.path, err)
+	}
+	if _, err := fd.Write(append(j, '\n')); err != nil {
+		fd.Close()
+		return fmt.Errorf("failed to write to dry-run file %q: %w", f.path, err)
+	}
+	return fd.Close()
+}
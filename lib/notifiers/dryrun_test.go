@@ -0,0 +1,143 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifiers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+// recordingDeliverySink keeps every captured delivery in memory.
+type recordingDeliverySink struct {
+	captured []*capturedDelivery
+}
+
+func (r *recordingDeliverySink) Capture(_ context.Context, cd *capturedDelivery) error {
+	r.captured = append(r.captured, cd)
+	return nil
+}
+
+// setDryRun turns on dry-run mode with the given sink for the duration of the test.
+func setDryRun(t *testing.T, ds deliverySink) {
+	t.Helper()
+	old := dryRun
+	dryRun = ds
+	t.Cleanup(func() { dryRun = old })
+}
+
+func TestNewDeliverySink(t *testing.T) {
+	for _, v := range []string{"log", "true", "1", "TRUE", "file:///tmp/deliveries.jsonl"} {
+		if ds, err := newDeliverySink(v); err != nil || ds == nil {
+			t.Errorf("newDeliverySink(%q) = %v, %v, want a sink", v, ds, err)
+		}
+	}
+	// These leave dry-run mode off.
+	for _, v := range []string{"", "false", "0", "False"} {
+		if ds, err := newDeliverySink(v); err != nil || ds != nil {
+			t.Errorf("newDeliverySink(%q) = %v, %v, want no sink", v, ds, err)
+		}
+	}
+	for _, v := range []string{"yes", "file://", "gs://some-bucket/deliveries"} {
+		if _, err := newDeliverySink(v); err == nil {
+			t.Errorf("newDeliverySink(%q) succeeded, want error", v)
+		}
+	}
+}
+
+func TestDeliveryClient(t *testing.T) {
+	if DryRun() || DeliveryClient() != http.DefaultClient {
+		t.Fatal("expected dry-run mode to be off by default")
+	}
+	if err := CaptureDelivery(context.Background(), "smtp", "somewhere", nil); err == nil {
+		t.Error("CaptureDelivery succeeded outside of dry-run mode, want error")
+	}
+
+	rs := new(recordingDeliverySink)
+	setDryRun(t, rs)
+
+	req, err := http.NewRequest(http.MethodPost, "https://hooks.example.com/services/some-secret-path?token=abc", strings.NewReader(`{"text": "hi"}`))
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer some-token")
+
+	resp, err := DeliveryClient().Do(req)
+	if err != nil {
+		t.Fatalf("Do failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("got status code %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	if err := CaptureDelivery(context.Background(), "smtp", "smtp.example.com:587 to someone@example.com", []byte("Subject: hi")); err != nil {
+		t.Errorf("CaptureDelivery failed: %v", err)
+	}
+
+	want := []*capturedDelivery{{
+		Kind:        "http",
+		Destination: "POST https://hooks.example.com",
+		Headers:     map[string]string{"Content-Type": "application/json", "Authorization": "[REDACTED]"},
+		Payload:     `{"text": "hi"}`,
+	}, {
+		Kind:        "smtp",
+		Destination: "smtp.example.com:587 to someone@example.com",
+		Payload:     "Subject: hi",
+	}}
+	if diff := cmp.Diff(want, rs.captured, cmpopts.IgnoreFields(capturedDelivery{}, "Time")); diff != "" {
+		t.Errorf("unexpected captured deliveries diff: (want- got+)\n%s", diff)
+	}
+}
+
+func TestFileDeliverySink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "deliveries.jsonl")
+	ds, err := newDeliverySink("file://" + path)
+	if err != nil {
+		t.Fatalf("newDeliverySink failed: %v", err)
+	}
+	for _, p := range []string{"first", "second"} {
+		if err := ds.Capture(context.Background(), &capturedDelivery{Kind: "http", Payload: p}); err != nil {
+			t.Fatalf("Capture failed: %v", err)
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open %q: %v", path, err)
+	}
+	defer f.Close()
+	var got []string
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		cd := new(capturedDelivery)
+		if err := json.Unmarshal(s.Bytes(), cd); err != nil {
+			t.Fatalf("failed to decode line %q: %v", s.Text(), err)
+		}
+		got = append(got, cd.Payload)
+	}
+	if diff := cmp.Diff([]string{"first", "second"}, got); diff != "" {
+		t.Errorf("unexpected payloads diff: (want- got+)\n%s", diff)
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifiers
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"time"
+)
+
+// healthCheckTimeout bounds how long a notifier's CheckHealth may take during a `/readyz` request.
+const healthCheckTimeout = 5 * time.Second
+
+// HealthChecker is an optional interface for Notifiers that can check the status of their dependencies (e.g. whether
+// their SMTP server can be dialed). It is called on every request to the `/readyz` endpoint.
+type HealthChecker interface {
+	// CheckHealth returns a non-nil error iff one of the notifier's dependencies is unavailable.
+	CheckHealth(context.Context) error
+}
+
+// healthCheck is the result of one of the checks that make up the readiness of a notifier. A failed check that is
+// only a warning does not make the notifier unready.
+type healthCheck struct {
+	Name    string `json:"name"`
+	OK      bool   `json:"ok"`
+	Warning bool   `json:"warning,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+type readiness struct {
+	Ready  bool           `json:"ready"`
+	Checks []*healthCheck `json:"checks"`
+}
+
+// checkReadiness reports whether the notifier's dependencies are healthy and whether it is shutting down, which are
+// the only checks that make it unready. Main exits unless the first Config loads, and the last good Config keeps being
+// served when a reload fails, so the errors of the latest attempts to load the Config, set up the notifier, and
+// refresh its secrets are only reported as warnings (and counted by the config_load_failures_total and
+// secret_fetch_failures_total metrics).
+func checkReadiness(ctx context.Context, r *reloadingNotifier, d *drainer) *readiness {
+	rd := &readiness{Ready: true}
+	add := func(name string, err error, warning bool) {
+		hc := &healthCheck{Name: name, OK: err == nil}
+		if err != nil {
+			hc.Error = err.Error()
+			hc.Warning = warning
+			rd.Ready = rd.Ready && warning
+		}
+		rd.Checks = append(rd.Checks, hc)
+	}
+
+	r.statusMtx.Lock()
+	configErr, setupErr, secretsErr := r.configErr, r.setupErr, r.secretsErr
+	r.statusMtx.Unlock()
+	add("config", configErr, true)
+	add("setup", setupErr, true)
+	add("secrets", secretsErr, true)
+
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+	add("notifier", r.CheckHealth(ctx), false)
+
+	var shutdownErr error
+	if d.isDraining() {
+		shutdownErr = errors.New("shutting down")
+	}
+	add("shutdown", shutdownErr, false)
+	return rd
+}
+
+// healthzHandler serves liveness checks, which succeed as long as the HTTP server is serving.
+func healthzHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprintln(w, "ok")
+	}
+}
+
+// readyzHandler serves readiness checks, which fail with a 503 if any of the checks of checkReadiness (other than its
+// warnings) do.
+func readyzHandler(r *reloadingNotifier, d *drainer) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		rd := checkReadiness(req.Context(), r, d)
+		status := http.StatusOK
+		if !rd.Ready {
+			status = http.StatusServiceUnavailable
+		}
+		writeJSON(w, status, rd)
+	}
+}
+
+// hello describes the running notifier and its Config.
+type hello struct {
+	Notifier    string     `json:"notifier"`
+	Version     string     `json:"version"`
+	StartTime   time.Time  `json:"startTime"`
+	CurrentTime time.Time  `json:"currentTime"`
+	Config      *configRef `json:"config"`
+}
+
+type configRef struct {
+	Name       string `json:"name"`
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Source     string `json:"source"`
+	// Fingerprint is the hash of the Config and templates, which changes whenever they are reloaded.
+	Fingerprint string    `json:"fingerprint"`
+	Routes      int       `json:"routes"`
+	LoadedAt    time.Time `json:"loadedAt"`
+}
+
+// hellozHandler serves the version of the notifier and the metadata of its current Config as JSON.
+func hellozHandler(notifier Notifier, r *reloadingNotifier, startTime time.Time) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		ld := r.current.Load()
+		h := &hello{
+			Notifier:    fmt.Sprintf("%T", notifier),
+			Version:     version(),
+			StartTime:   startTime,
+			CurrentTime: time.Now(),
+			Config: &configRef{
+				APIVersion:  ld.cfg.APIVersion,
+				Kind:        ld.cfg.Kind,
+				Source:      r.loader.location(),
+				Fingerprint: hex.EncodeToString(ld.fingerprint[:]),
+				Routes:      len(ld.cfg.Spec.Routes()),
+				LoadedAt:    ld.loadedAt,
+			},
+		}
+		if ld.cfg.Metadata != nil {
+			h.Config.Name = ld.cfg.Metadata.Name
+		}
+		writeJSON(w, http.StatusOK, h)
+	}
+}
+
+// version returns the version of the notifier binary's module or, for builds from a checkout, its VCS revision.
+func version() string {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	if v := bi.Main.Version; v != "" && v != "(devel)" {
+		return v
+	}
+	for _, s := range bi.Settings {
+		if s.Key == "vcs.revision" {
+			return s.Value
+		}
+	}
+	return "(devel)"
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		Errorf(context.Background(), "failed to write JSON response: %v", err)
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifiers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// healthCheckingNotifier is a setUpCountingNotifier that reports the (shared) health of its dependencies.
+type healthCheckingNotifier struct {
+	setUpCountingNotifier
+	health *error
+}
+
+func (h *healthCheckingNotifier) CheckHealth(_ context.Context) error {
+	return *h.health
+}
+
+func newHealthTestNotifier(t *testing.T) (*reloadingNotifier, *fakeGCSReaderFactory, *error) {
+	t.Helper()
+	grf := &fakeGCSReaderFactory{
+		data: map[string]string{
+			reloadConfigPath:       reloadConfigYAML("cloud-build-notifiers/v1", "v1"),
+			"gs://bucket/template": "{{.Build.Id}}",
+		},
+	}
+	var setUps int
+	var health error
+	n := &healthCheckingNotifier{setUpCountingNotifier: setUpCountingNotifier{setUps: &setUps}, health: &health}
+	cl := &configLoader{notifier: n, path: reloadConfigPath, src: &configSource{grf: grf}, sg: newCachingSecretGetter(new(setupCheckSecretGetter), 0)}
+	ld, err := cl.load(context.Background())
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	return newReloadingNotifier(cl, ld), grf, &health
+}
+
+func TestReadyz(t *testing.T) {
+	ctx := context.Background()
+	rn, grf, health := newHealthTestNotifier(t)
+	d := new(drainer)
+
+	for _, step := range []struct {
+		name       string
+		change     func()
+		wantStatus int
+		wantFailed []string
+	}{{
+		name:       "ready",
+		change:     func() {},
+		wantStatus: http.StatusOK,
+	}, {
+		// The last good config keeps being served, so a failed reload is only a warning.
+		name: "invalid config",
+		change: func() {
+			grf.data[reloadConfigPath] = reloadConfigYAML("bad-api-version", "v1")
+			rn.reload(ctx)
+		},
+		wantStatus: http.StatusOK,
+		wantFailed: []string{"config"},
+	}, {
+		name: "failed SetUp",
+		change: func() {
+			grf.data[reloadConfigPath] = reloadConfigYAML("cloud-build-notifiers/v1", "bad")
+			rn.reload(ctx)
+		},
+		wantStatus: http.StatusOK,
+		wantFailed: []string{"setup"},
+	}, {
+		// A warning does not keep an unhealthy notifier ready.
+		name:       "failed SetUp and unhealthy notifier",
+		change:     func() { *health = errors.New("connection refused") },
+		wantStatus: http.StatusServiceUnavailable,
+		wantFailed: []string{"setup", "notifier"},
+	}, {
+		name: "fixed config",
+		change: func() {
+			*health = nil
+			grf.data[reloadConfigPath] = reloadConfigYAML("cloud-build-notifiers/v1", "v2")
+			rn.reload(ctx)
+		},
+		wantStatus: http.StatusOK,
+	}, {
+		name:       "unhealthy notifier",
+		change:     func() { *health = errors.New("connection refused") },
+		wantStatus: http.StatusServiceUnavailable,
+		wantFailed: []string{"notifier"},
+	}, {
+		name: "shutting down",
+		change: func() {
+			*health = nil
+			d.drain(ctx)
+		},
+		wantStatus: http.StatusServiceUnavailable,
+		wantFailed: []string{"shutdown"},
+	}} {
+		step.change()
+
+		w := httptest.NewRecorder()
+		readyzHandler(rn, d)(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		if s := w.Result().StatusCode; s != step.wantStatus {
+			t.Errorf("%s: got status %d, want %d", step.name, s, step.wantStatus)
+		}
+
+		rd := new(readiness)
+		if err := json.NewDecoder(w.Body).Decode(rd); err != nil {
+			t.Fatalf("%s: failed to decode readiness: %v", step.name, err)
+		}
+		var failed []string
+		for _, c := range rd.Checks {
+			if !c.OK {
+				failed = append(failed, c.Name)
+			}
+		}
+		if diff := cmp.Diff(step.wantFailed, failed); diff != "" {
+			t.Errorf("%s: unexpected failed checks diff: (want- got+)\n%s", step.name, diff)
+		}
+	}
+}
+
+func TestHelloz(t *testing.T) {
+	rn, _, _ := newHealthTestNotifier(t)
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	w := httptest.NewRecorder()
+	hellozHandler(new(setUpCountingNotifier), rn, start)(w, httptest.NewRequest(http.MethodGet, "/helloz", nil))
+	if s := w.Result().StatusCode; s != http.StatusOK {
+		t.Fatalf("got status %d, want %d", s, http.StatusOK)
+	}
+
+	got := new(hello)
+	if err := json.NewDecoder(w.Body).Decode(got); err != nil {
+		t.Fatalf("failed to decode hello: %v", err)
+	}
+	if got.Notifier != "*notifiers.setUpCountingNotifier" || !got.StartTime.Equal(start) || got.Version == "" {
+		t.Errorf("got unexpected notifier metadata: %+v", got)
+	}
+	want := &configRef{
+		APIVersion: "cloud-build-notifiers/v1",
+		Kind:       "TestNotifier",
+		Source:     `path "gs://bucket/config.yaml"`,
+		Routes:     1,
+	}
+	if got.Config.Fingerprint == "" || got.Config.LoadedAt.IsZero() {
+		t.Errorf("expected a fingerprint and load time, got %+v", got.Config)
+	}
+	got.Config.Fingerprint, got.Config.LoadedAt = "", time.Time{}
+	if diff := cmp.Diff(want, got.Config); diff != "" {
+		t.Errorf("unexpected config metadata diff: (want- got+)\n%s", diff)
+	}
+}
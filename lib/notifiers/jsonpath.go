@@ -9,8 +9,13 @@ import (
 	"reflect"
 	"strings"
 	"sync"
+	"time"
 
 	cbpb "cloud.google.com/go/cloudbuild/apiv1/v2/cloudbuildpb"
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"google.golang.org/protobuf/types/known/structpb"
 	"k8s.io/client-go/third_party/forked/golang/template"
 	"k8s.io/client-go/util/jsonpath"
 )
@@ -26,15 +31,32 @@ type inputAndJSONPath struct {
 	p string             // The user-provided path.
 }
 
+type inputAndCELProgram struct {
+	prg cel.Program       // The program that was compiled from that expression.
+	p   string            // The user-provided `cel(...)` expression.
+	env map[string]string // The environment variables that the program sees as `env`.
+}
+
 type jpResolver struct {
-	mtx sync.RWMutex
-	jps map[string]*inputAndJSONPath // Map of _SOME_SUBST_NAME => its inputAndJSONPath.
-	cfg *Config
+	mtx  sync.RWMutex
+	jps  map[string]*inputAndJSONPath   // Map of _SOME_SUBST_NAME => its inputAndJSONPath.
+	cels map[string]*inputAndCELProgram // Map of _SOME_SUBST_NAME => its inputAndCELProgram.
+	cfg  *Config
 }
 
 func newResolver(cfg *Config) (BindingResolver, error) {
 	jps := map[string]*inputAndJSONPath{}
+	cels := map[string]*inputAndCELProgram{}
 	for name, path := range cfg.Spec.Notification.Params {
+		if isCELBinding(path) {
+			prg, err := makeCELBinding(path)
+			if err != nil {
+				return nil, err
+			}
+			cels[name] = &inputAndCELProgram{prg: prg, p: path, env: celEnv()}
+			continue
+		}
+
 		p, err := makeJSONPath(path)
 		if err != nil {
 			return nil, fmt.Errorf("failed to derive substitution path from %q: %v", path, err)
@@ -50,12 +72,20 @@ func newResolver(cfg *Config) (BindingResolver, error) {
 		}
 	}
 	return &jpResolver{
-		jps: jps,
-		cfg: cfg,
+		jps:  jps,
+		cels: cels,
+		cfg:  cfg,
 	}, nil
 }
 
 func (j *jpResolver) Resolve(ctx context.Context, sg SecretGetter, build *cbpb.Build) (map[string]string, error) {
+	ctx, span := StartSpan(ctx, "BindingResolver.Resolve")
+	ret, err := j.resolve(ctx, sg, build)
+	EndSpan(span, err)
+	return ret, err
+}
+
+func (j *jpResolver) resolve(ctx context.Context, sg SecretGetter, build *cbpb.Build) (map[string]string, error) {
 	j.mtx.RLock()
 	defer j.mtx.RUnlock()
 
@@ -84,9 +114,71 @@ func (j *jpResolver) Resolve(ctx context.Context, sg SecretGetter, build *cbpb.B
 		}
 		ret[name] = buf.String()
 	}
+
+	for name, c := range j.cels {
+		out, _, err := c.prg.Eval(celVars(ctx, build, c.env))
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate %q with expression %q: %v", name, c.p, err)
+		}
+		v, err := celValueString(out)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert the result of %q with expression %q: %v", name, c.p, err)
+		}
+		ret[name] = v
+	}
 	return ret, nil
 }
 
+func isCELBinding(path string) bool {
+	return strings.HasPrefix(path, "cel(") && strings.HasSuffix(path, ")")
+}
+
+// makeCELBinding compiles the CEL expression of a `cel(...)` param. It is compiled in the same environment as filters,
+// but its result can be of any type.
+func makeCELBinding(path string) (cel.Program, error) {
+	expr := strings.TrimSuffix(strings.TrimPrefix(path, "cel("), ")")
+	env, err := newCELEnv()
+	if err != nil {
+		return nil, err
+	}
+	ast, issues := env.Compile(expr)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("failed to compile CEL expression from %q: %w", path, issues.Err())
+	}
+	prg, err := env.Program(ast, cel.EvalOptions(cel.OptOptimize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CEL program from %q: %w", path, err)
+	}
+	return prg, nil
+}
+
+// celValueString formats the result of a `cel(...)` param like the results of JSONPath params: scalars as text, and
+// lists, maps, and messages as JSON.
+func celValueString(v ref.Val) (string, error) {
+	switch t := v.(type) {
+	case types.String:
+		return string(t), nil
+	case types.Bool, types.Int, types.Uint, types.Double:
+		return fmt.Sprint(t.Value()), nil
+	case types.Duration:
+		return t.Duration.String(), nil
+	case types.Timestamp:
+		return t.Time.Format(time.RFC3339Nano), nil
+	case types.Null:
+		return "null", nil
+	}
+
+	native, err := v.ConvertToNative(reflect.TypeOf(&structpb.Value{}))
+	if err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(native.(*structpb.Value).AsInterface())
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
 func makeJSONPath(path string) (string, error) {
 	if !strings.HasPrefix(path, "$(") || !strings.HasSuffix(path, ")") {
 		return "", fmt.Errorf("expected %q to start with `$(` and end with `)` for a valid JSONPath expression", path)
@@ -4,9 +4,12 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	cbpb "cloud.google.com/go/cloudbuild/apiv1/v2/cloudbuildpb"
 	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 func TestNewResolver(t *testing.T) {
@@ -45,6 +48,18 @@ func TestNewResolverErrors(t *testing.T) {
 				"PIZZA": "hello.goodbye",
 			},
 		},
+		{
+			name: "bad CEL syntax",
+			substs: map[string]string{
+				"PIZZA": "cel(build.id +)",
+			},
+		},
+		{
+			name: "CEL unknown field",
+			substs: map[string]string{
+				"PIZZA": "cel(build.banana)",
+			},
+		},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
 			cfg := &Config{
@@ -167,6 +182,12 @@ func TestResolveErrors(t *testing.T) {
 			"_FOO": "$(build.banana)",
 		},
 		build: &cbpb.Build{Id: "id"},
+	}, {
+		name: "CEL for unknown subst",
+		substs: map[string]string{
+			"_FOO": "cel(build.substitutions['DNE'])",
+		},
+		build: &cbpb.Build{Substitutions: map[string]string{"_HELLO": "world"}},
 	}, {
 		name: "path for unknown secret",
 		substs: map[string]string{
@@ -209,3 +230,66 @@ func TestResolveErrors(t *testing.T) {
 		})
 	}
 }
+
+func TestResolveCEL(t *testing.T) {
+	t.Setenv("NOTIFIER_DEPLOY_ENV", "prod")
+	t.Setenv("VAULT_TOKEN", "some-token")
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	substs := map[string]string{
+		"_REF":      `cel(build.substitutions.SHORT_SHA + "@" + build.substitutions.BRANCH_NAME)`,
+		"_MINUTES":  "cel(duration(build).getMinutes())",
+		"_SEVERITY": `cel(build.status == Build.Status.SUCCESS ? "info" : "error")`,
+		"_DEFAULT":  `cel(substitution("_OWNER", "nobody"))`,
+		"_STEPS":    "cel(build.steps.map(s, s.name))",
+		"_TIMEOUT":  "cel(build.timeout)",
+		"_FINISHED": "cel(build.finish_time)",
+		"_BRANCH":   "$(build.substitutions.BRANCH_NAME)",
+		"_ENV":      `cel(env.NOTIFIER_DEPLOY_ENV + (has(env.VAULT_TOKEN) ? " with VAULT_TOKEN" : ""))`,
+	}
+	cfg := &Config{
+		Spec: &Spec{
+			Notification: &Notification{
+				Params: substs,
+			},
+		},
+	}
+
+	r, err := newResolver(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	build := &cbpb.Build{
+		Status:     cbpb.Build_FAILURE,
+		Steps:      []*cbpb.BuildStep{{Name: "foo"}, {Name: "bar"}},
+		StartTime:  timestamppb.New(start),
+		FinishTime: timestamppb.New(start.Add(42*time.Minute + 30*time.Second)),
+		Timeout:    durationpb.New(time.Hour),
+		Substitutions: map[string]string{
+			"BRANCH_NAME": "my-branch",
+			"SHORT_SHA":   "abc1234",
+		},
+	}
+
+	gotResolved, err := r.Resolve(context.Background(), new(fakeSecretGetter), build)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantResolved := map[string]string{
+		"_REF":      "abc1234@my-branch",
+		"_MINUTES":  "42",
+		"_SEVERITY": "error",
+		"_DEFAULT":  "nobody",
+		"_STEPS":    `["foo","bar"]`,
+		"_TIMEOUT":  "1h0m0s",
+		"_FINISHED": "2026-01-02T03:46:35Z",
+		"_BRANCH":   "my-branch",
+		// Like filters, `cel(...)` params only see the NOTIFIER_ environment variables.
+		"_ENV": "prod",
+	}
+
+	if diff := cmp.Diff(wantResolved, gotResolved); diff != "" {
+		t.Errorf("unxpected diff from resolving CEL:\n%s", diff)
+	}
+}
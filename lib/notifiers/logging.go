@@ -0,0 +1,235 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifiers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	cbpb "cloud.google.com/go/cloudbuild/apiv1/v2/cloudbuildpb"
+	log "github.com/golang/glog"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// maxLoggedPayloadBytes caps the size of Builds and message bodies in logs.
+	maxLoggedPayloadBytes = 4096
+	redacted              = "[REDACTED]"
+)
+
+// severity is a Cloud Logging LogSeverity.
+type severity string
+
+const (
+	severityDebug    severity = "DEBUG"
+	severityInfo     severity = "INFO"
+	severityWarning  severity = "WARNING"
+	severityError    severity = "ERROR"
+	severityCritical severity = "CRITICAL"
+)
+
+var (
+	logMtx sync.Mutex
+	// logOutput is where JSON log entries are written. Cloud Run and GKE pick up JSON lines from stderr.
+	logOutput io.Writer = os.Stderr
+	// jsonLogs is true iff logs are written as Cloud Logging JSON rather than through glog's text format.
+	jsonLogs = true
+	// logProject is the GCP project of the trace IDs in log entries.
+	logProject string
+)
+
+// configureLogging sets the log format from LOG_FORMAT, which is either `json` (the default) or `text`.
+// PROJECT_ID is used to link log entries to Cloud Trace.
+func configureLogging() error {
+	// These are read with os.Getenv since GetEnv logs, which needs the format to be known.
+	switch f := os.Getenv("LOG_FORMAT"); f {
+	case "", "json":
+		jsonLogs = true
+	case "text":
+		jsonLogs = false
+	default:
+		return fmt.Errorf("expected LOG_FORMAT %q to be one of `json` or `text`", f)
+	}
+	logProject = os.Getenv("PROJECT_ID")
+	return nil
+}
+
+// logFields are the fields of the message and Build being handled, which are added to every log entry.
+type logFields struct {
+	messageID string
+	buildID   string
+	projectID string
+}
+
+type logFieldsKey struct{}
+
+func withMessageLogFields(ctx context.Context, messageID string) context.Context {
+	return context.WithValue(ctx, logFieldsKey{}, &logFields{messageID: messageID})
+}
+
+func withBuildLogFields(ctx context.Context, build *cbpb.Build) context.Context {
+	f := &logFields{buildID: build.GetId(), projectID: build.GetProjectId()}
+	if prev, ok := ctx.Value(logFieldsKey{}).(*logFields); ok {
+		f.messageID = prev.messageID
+	}
+	return context.WithValue(ctx, logFieldsKey{}, f)
+}
+
+// logEntry is a log entry in the Cloud Logging structured logging format.
+// See https://cloud.google.com/logging/docs/structured-logging.
+type logEntry struct {
+	Severity       severity        `json:"severity"`
+	Message        string          `json:"message"`
+	Time           time.Time       `json:"time"`
+	SourceLocation *sourceLocation `json:"logging.googleapis.com/sourceLocation,omitempty"`
+	Trace          string          `json:"logging.googleapis.com/trace,omitempty"`
+	SpanID         string          `json:"logging.googleapis.com/spanId,omitempty"`
+	TraceSampled   bool            `json:"logging.googleapis.com/trace_sampled,omitempty"`
+	MessageID      string          `json:"messageId,omitempty"`
+	BuildID        string          `json:"buildId,omitempty"`
+	ProjectID      string          `json:"projectId,omitempty"`
+}
+
+type sourceLocation struct {
+	File string `json:"file"`
+	Line string `json:"line"`
+}
+
+// Debugf logs at the DEBUG severity, but only if glog's verbosity (`-v`) is at least 2.
+func Debugf(ctx context.Context, format string, args ...interface{}) {
+	if log.V(2) {
+		logf(ctx, severityDebug, format, args...)
+	}
+}
+
+// Infof logs at the INFO severity.
+func Infof(ctx context.Context, format string, args ...interface{}) {
+	logf(ctx, severityInfo, format, args...)
+}
+
+// Warningf logs at the WARNING severity.
+func Warningf(ctx context.Context, format string, args ...interface{}) {
+	logf(ctx, severityWarning, format, args...)
+}
+
+// Errorf logs at the ERROR severity.
+func Errorf(ctx context.Context, format string, args ...interface{}) {
+	logf(ctx, severityError, format, args...)
+}
+
+// Fatalf logs at the CRITICAL severity and exits.
+func Fatalf(ctx context.Context, format string, args ...interface{}) {
+	logf(ctx, severityCritical, format, args...)
+	log.Flush()
+	os.Exit(1)
+}
+
+// logf writes a log entry with the fields (and trace) of the given context.
+func logf(ctx context.Context, sev severity, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if !jsonLogs {
+		// The depth skips logf and its exported caller.
+		switch sev {
+		case severityWarning:
+			log.WarningDepth(2, msg)
+		case severityError, severityCritical:
+			log.ErrorDepth(2, msg)
+		default:
+			log.InfoDepth(2, msg)
+		}
+		return
+	}
+
+	e := &logEntry{Severity: sev, Message: msg, Time: time.Now()}
+	if _, file, line, ok := runtime.Caller(2); ok {
+		e.SourceLocation = &sourceLocation{File: file, Line: fmt.Sprint(line)}
+	}
+	if f, ok := ctx.Value(logFieldsKey{}).(*logFields); ok {
+		e.MessageID, e.BuildID, e.ProjectID = f.messageID, f.buildID, f.projectID
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		e.Trace = sc.TraceID().String()
+		if logProject != "" {
+			e.Trace = fmt.Sprintf("projects/%s/traces/%s", logProject, e.Trace)
+		}
+		e.SpanID = sc.SpanID().String()
+		e.TraceSampled = sc.IsSampled()
+	}
+
+	b, err := json.Marshal(e)
+	if err != nil {
+		// This cannot happen for the above fields, but never lose the message.
+		b = []byte(fmt.Sprintf(`{"severity": %q, "message": %q}`, sev, msg))
+	}
+	logMtx.Lock()
+	defer logMtx.Unlock()
+	logOutput.Write(append(b, '\n'))
+}
+
+// FormatBuild returns the given Build as JSON for logs, with the values of environment variables, user-defined
+// substitutions, and secrets redacted, and truncated to a few KiB.
+func FormatBuild(build *cbpb.Build) string {
+	b := proto.Clone(build).(*cbpb.Build)
+	for k := range b.GetSubstitutions() {
+		// User-defined substitutions start with an underscore, unlike the built-in ones (e.g. BRANCH_NAME).
+		if strings.HasPrefix(k, "_") {
+			b.Substitutions[k] = redacted
+		}
+	}
+	if b.GetOptions() != nil {
+		b.Options.Env = redactEnv(b.Options.Env)
+	}
+	for _, s := range b.GetSteps() {
+		s.Env = redactEnv(s.Env)
+	}
+	for _, s := range b.GetSecrets() {
+		for k := range s.GetSecretEnv() {
+			s.SecretEnv[k] = []byte(redacted)
+		}
+	}
+
+	j, err := protojson.Marshal(b)
+	if err != nil {
+		return fmt.Sprintf("<failed to format Build: %v>", err)
+	}
+	return truncatePayload(j)
+}
+
+// redactEnv redacts the values of the given `KEY=VALUE` environment variables.
+func redactEnv(env []string) []string {
+	var out []string
+	for _, e := range env {
+		k, _, _ := strings.Cut(e, "=")
+		out = append(out, k+"="+redacted)
+	}
+	return out
+}
+
+// truncatePayload returns the given payload as a string, truncated to maxLoggedPayloadBytes.
+func truncatePayload(p []byte) string {
+	if len(p) <= maxLoggedPayloadBytes {
+		return string(p)
+	}
+	return fmt.Sprintf("%s... (%d more bytes)", p[:maxLoggedPayloadBytes], len(p)-maxLoggedPayloadBytes)
+}
@@ -0,0 +1,118 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifiers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	cbpb "cloud.google.com/go/cloudbuild/apiv1/v2/cloudbuildpb"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// captureLogs redirects the JSON logs to the returned buffer for the duration of the test.
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	buf := new(bytes.Buffer)
+	prevOutput, prevJSON, prevProject := logOutput, jsonLogs, logProject
+	logOutput, jsonLogs, logProject = buf, true, "my-project"
+	t.Cleanup(func() { logOutput, jsonLogs, logProject = prevOutput, prevJSON, prevProject })
+	return buf
+}
+
+func TestJSONLogs(t *testing.T) {
+	buf := captureLogs(t)
+
+	tid, err := trace.TraceIDFromHex(incomingTraceID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sid, err := trace.SpanIDFromHex(incomingSpanID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    tid,
+		SpanID:     sid,
+		TraceFlags: trace.FlagsSampled,
+	}))
+	ctx = withMessageLogFields(ctx, "message-id")
+	ctx = withBuildLogFields(ctx, &cbpb.Build{Id: "build-id", ProjectId: "build-project"})
+
+	Warningf(ctx, "something %s happened", "bad")
+	Infof(context.Background(), "no fields")
+
+	var got []*logEntry
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		e := new(logEntry)
+		if err := json.Unmarshal([]byte(line), e); err != nil {
+			t.Fatalf("failed to unmarshal log line %q: %v", line, err)
+		}
+		if e.SourceLocation == nil || !strings.HasSuffix(e.SourceLocation.File, "logging_test.go") {
+			t.Errorf("got source location %+v, want one in logging_test.go", e.SourceLocation)
+		}
+		got = append(got, e)
+	}
+
+	want := []*logEntry{{
+		Severity:     severityWarning,
+		Message:      "something bad happened",
+		Trace:        "projects/my-project/traces/" + incomingTraceID,
+		SpanID:       incomingSpanID,
+		TraceSampled: true,
+		MessageID:    "message-id",
+		BuildID:      "build-id",
+		ProjectID:    "build-project",
+	}, {
+		Severity: severityInfo,
+		Message:  "no fields",
+	}}
+	if diff := cmp.Diff(want, got, cmpopts.IgnoreFields(logEntry{}, "Time", "SourceLocation")); diff != "" {
+		t.Errorf("unexpected log entries diff: (want- got+)\n%s", diff)
+	}
+}
+
+func TestFormatBuild(t *testing.T) {
+	build := &cbpb.Build{
+		Id:            "build-id",
+		Substitutions: map[string]string{"BRANCH_NAME": "main", "_API_KEY": "hunter2"},
+		Options:       &cbpb.BuildOptions{Env: []string{"TOKEN=hunter2"}},
+		Steps:         []*cbpb.BuildStep{{Name: "gcr.io/cloud-builders/docker", Env: []string{"PASSWORD=hunter2"}}},
+		Secrets:       []*cbpb.Secret{{KmsKeyName: "key", SecretEnv: map[string][]byte{"SECRET": []byte("hunter2")}}},
+	}
+
+	got := FormatBuild(build)
+	if strings.Contains(got, "hunter2") || strings.Contains(got, "aHVudGVyMg") { // The latter is base64 for bytes fields.
+		t.Errorf("FormatBuild leaked a secret: %s", got)
+	}
+	for _, want := range []string{"build-id", "main", "_API_KEY", "TOKEN=" + redacted, "PASSWORD=" + redacted} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected FormatBuild to contain %q, got: %s", want, got)
+		}
+	}
+	if build.Substitutions["_API_KEY"] != "hunter2" || build.Options.Env[0] != "TOKEN=hunter2" {
+		t.Errorf("FormatBuild modified the given Build: %v", build)
+	}
+
+	long := FormatBuild(&cbpb.Build{Id: strings.Repeat("x", 2*maxLoggedPayloadBytes)})
+	if len(long) > maxLoggedPayloadBytes+100 || !strings.Contains(long, "more bytes)") {
+		t.Errorf("expected FormatBuild to truncate long Builds, got %d bytes", len(long))
+	}
+}
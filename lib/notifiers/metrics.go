@@ -0,0 +1,137 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifiers
+
+import (
+	"net/http"
+	"reflect"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const metricsNamespace = "cloud_build_notifier"
+
+// Label values of messagesReceived.
+const (
+	messageDecoded     = "decoded"
+	messageDecodeError = "decode_error"
+	messageIgnored     = "ignored"
+	messageDuplicate   = "duplicate"
+)
+
+// Label values of filterEvaluations.
+const (
+	filterMatch = "match"
+	filterMiss  = "miss"
+	filterError = "error"
+)
+
+// Label values of sendDuration.
+const (
+	sendSuccess = "success"
+	sendFailure = "failure"
+)
+
+// Label values of rateLimited.
+const (
+	rateLimitDelayed    = "delayed"
+	rateLimitRejected   = "rejected"
+	rateLimitSummarized = "summarized"
+	rateLimitDropped    = "dropped"
+)
+
+// Label values of quietHours.
+const (
+	quietHoursSuppressed = "suppressed"
+	quietHoursDeferred   = "deferred"
+)
+
+var (
+	// metricsRegistry holds every metric of the notifier and is what `/metrics` serves.
+	metricsRegistry = prometheus.NewRegistry()
+
+	messagesReceived = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "messages_received_total",
+		Help:      "Pub/Sub messages received, by result: decoded, decode_error, ignored (IGNORE_BAD_MESSAGES), or duplicate.",
+	}, []string{"result"})
+
+	filterEvaluations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "filter_evaluations_total",
+		Help:      "CEL filter evaluations, by result: match, miss, or error.",
+	}, []string{"result"})
+
+	sendDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "send_notification_duration_seconds",
+		Help:      "Latency of SendNotification attempts, by notifier type and outcome: success or failure.",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 2, 12),
+	}, []string{"notifier", "outcome"})
+
+	secretFetchFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "secret_fetch_failures_total",
+		Help:      "Failed secret fetches.",
+	})
+
+	configLoadFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "config_load_failures_total",
+		Help:      "Failed config (re)loads.",
+	})
+
+	rateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "rate_limited_notifications_total",
+		Help:      "Notifications over their destination's rate limit, by outcome: delayed, rejected (nacked), summarized, or dropped.",
+	}, []string{"outcome"})
+
+	quietHours = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "quiet_hours_notifications_total",
+		Help:      "Notifications during a route's quiet hours, by outcome: suppressed or deferred.",
+	}, []string{"outcome"})
+)
+
+func init() {
+	metricsRegistry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		messagesReceived,
+		filterEvaluations,
+		sendDuration,
+		secretFetchFailures,
+		configLoadFailures,
+		rateLimited,
+		quietHours,
+	)
+}
+
+// metricsHandler serves the notifier's metrics in the Prometheus exposition format.
+func metricsHandler() http.Handler {
+	return promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{})
+}
+
+// notifierType returns the name of the given notifier's type (e.g. `slackNotifier`) for use as a metric label.
+func notifierType(notifier Notifier) string {
+	t := reflect.TypeOf(notifier)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Name()
+}
@@ -0,0 +1,154 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifiers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	cbpb "cloud.google.com/go/cloudbuild/apiv1/v2/cloudbuildpb"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// failingNotifier fails every SendNotification call.
+type failingNotifier struct{}
+
+func (f *failingNotifier) SetUp(_ context.Context, _ *Config, _ string, _ SecretGetter, _ BindingResolver) error {
+	return nil
+}
+
+func (f *failingNotifier) SendNotification(_ context.Context, _ *cbpb.Build) error {
+	return errors.New("failed to send")
+}
+
+func TestHandleMessageMetrics(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		notifier     Notifier
+		params       *receiverParams
+		data         string
+		wantReceived map[string]float64
+		wantSends    map[string]int
+	}{{
+		name:         "sent",
+		notifier:     new(eventRecordingNotifier),
+		params:       &receiverParams{notifierType: "eventRecordingNotifier"},
+		data:         ceBuildJSON,
+		wantReceived: map[string]float64{messageDecoded: 1},
+		wantSends:    map[string]int{sendSuccess: 1},
+	}, {
+		name:         "failed",
+		notifier:     new(failingNotifier),
+		params:       &receiverParams{notifierType: "failingNotifier", retry: &RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond}},
+		data:         ceBuildJSON,
+		wantReceived: map[string]float64{messageDecoded: 1},
+		wantSends:    map[string]int{sendFailure: 2},
+	}, {
+		name:         "decode error",
+		notifier:     new(eventRecordingNotifier),
+		params:       &receiverParams{notifierType: "eventRecordingNotifier"},
+		data:         "not a Build",
+		wantReceived: map[string]float64{messageDecodeError: 1},
+	}, {
+		name:         "ignored",
+		notifier:     new(eventRecordingNotifier),
+		params:       &receiverParams{notifierType: "eventRecordingNotifier", ignoreBadMessages: true},
+		data:         "not a Build",
+		wantReceived: map[string]float64{messageIgnored: 1},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			results := []string{messageDecoded, messageDecodeError, messageIgnored, messageDuplicate}
+			before := map[string]float64{}
+			for _, r := range results {
+				before[r] = testutil.ToFloat64(messagesReceived.WithLabelValues(r))
+			}
+			beforeSends := map[string]int{}
+			for _, o := range []string{sendSuccess, sendFailure} {
+				beforeSends[o] = histogramCount(t, sendDuration.WithLabelValues(tc.params.notifierType, o))
+			}
+
+			msg := &pubSubPushMessage{Data: []byte(tc.data), ID: "message-id"}
+			handleMessage(context.Background(), tc.notifier, tc.params, msg, nil)
+
+			for _, r := range results {
+				if got := testutil.ToFloat64(messagesReceived.WithLabelValues(r)) - before[r]; got != tc.wantReceived[r] {
+					t.Errorf("messages_received_total{result=%q} increased by %v, want %v", r, got, tc.wantReceived[r])
+				}
+			}
+			for _, o := range []string{sendSuccess, sendFailure} {
+				if got := histogramCount(t, sendDuration.WithLabelValues(tc.params.notifierType, o)) - beforeSends[o]; got != tc.wantSends[o] {
+					t.Errorf("send_notification_duration_seconds{outcome=%q} count increased by %d, want %d", o, got, tc.wantSends[o])
+				}
+			}
+		})
+	}
+}
+
+// histogramCount returns the number of observations of the given histogram.
+func histogramCount(t *testing.T, o prometheus.Observer) int {
+	t.Helper()
+	h, ok := o.(prometheus.Histogram)
+	if !ok {
+		t.Fatalf("got %T, want a prometheus.Histogram", o)
+	}
+	var m dto.Metric
+	if err := h.Write(&m); err != nil {
+		t.Fatalf("failed to write histogram: %v", err)
+	}
+	return int(m.GetHistogram().GetSampleCount())
+}
+
+func TestMetricsHandler(t *testing.T) {
+	messagesReceived.WithLabelValues(messageDecoded)
+	filterEvaluations.WithLabelValues(filterMatch)
+
+	srv := httptest.NewServer(metricsHandler())
+	defer srv.Close()
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("failed to get metrics: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read metrics: %v", err)
+	}
+
+	for _, want := range []string{
+		"cloud_build_notifier_messages_received_total",
+		"cloud_build_notifier_filter_evaluations_total",
+		"cloud_build_notifier_secret_fetch_failures_total",
+		"cloud_build_notifier_config_load_failures_total",
+		"go_goroutines",
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("expected metrics to contain %q, got:\n%s", want, body)
+		}
+	}
+}
+
+func TestNotifierType(t *testing.T) {
+	if got, want := notifierType(new(failingNotifier)), "failingNotifier"; got != want {
+		t.Errorf("notifierType = %q, want %q", got, want)
+	}
+}
@@ -24,22 +24,30 @@ import (
 	"html/template"
 	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
+	"os/signal"
+	"reflect"
 	"regexp"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	cbpb "cloud.google.com/go/cloudbuild/apiv1/v2/cloudbuildpb"
+	"cloud.google.com/go/pubsub"
 	secretmanager "cloud.google.com/go/secretmanager/apiv1"
 	smpb "cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
-	"cloud.google.com/go/storage"
-	log "github.com/golang/glog"
 	"github.com/google/cel-go/cel"
 	"github.com/google/cel-go/checker/decls"
+	"github.com/google/cel-go/common/types"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/protobuf/encoding/protojson"
-	"google.golang.org/protobuf/encoding/prototext"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/protoadapt"
 	"gopkg.in/yaml.v2"
@@ -66,6 +74,12 @@ var (
 var (
 	smoketest  = flag.Bool("smoketest", false, "If true, Main will simply log the notifier type and exit.")
 	setupCheck = flag.Bool("setup_check", false, "If true, the configuration YAML is read from stdin and notifier.SetUp is called in a faked-out way. The smoketest flag takes priority over this one.")
+
+	setupCheckBuilds = flag.String("setup_check_builds", "", "Comma-separated paths or globs of sample Builds (YAML or JSON) that -setup_check checks the filter, params, and template of every notification route against.")
+
+	renderBuild    = flag.String("render", "", "If set, the Build at this local path (YAML or JSON) is rendered with the -render_config Config, and the payload of every notification route is printed instead of being sent.")
+	renderConfig   = flag.String("render_config", "", "The Config (path or URI) that -render uses. Defaults to CONFIG_PATH.")
+	renderTemplate = flag.String("render_template", "", "If set, -render uses the template at this local path for every notification route.")
 )
 
 var (
@@ -86,9 +100,25 @@ type Metadata struct {
 }
 
 // Spec is the data container for the fields that are relevant to the functionality of the notifier.
+// Exactly one of Notification or Notifications should be set.
 type Spec struct {
-	Notification *Notification `yaml:"notification"`
-	Secrets      []*Secret     `yaml:"secrets"`
+	Notification  *Notification      `yaml:"notification"`
+	Notifications []*Notification    `yaml:"notifications"`
+	Secrets       []*Secret          `yaml:"secrets"`
+	Retry         *RetryPolicy       `yaml:"retry"`
+	DeadLetter    *DeadLetterConfig  `yaml:"deadLetter"`
+	Dedupe        *DedupeConfig      `yaml:"dedupe"`
+	PushAuth      *PushAuthConfig    `yaml:"pushAuth"`
+	RateLimit     *RateLimitConfig   `yaml:"rateLimit"`
+	Transitions   *TransitionsConfig `yaml:"transitions"`
+}
+
+// Routes returns the notification routes of the Spec, i.e. either the single `notification` or the `notifications` list.
+func (s *Spec) Routes() []*Notification {
+	if s.Notification != nil {
+		return []*Notification{s.Notification}
+	}
+	return s.Notifications
 }
 
 // Notification is the data container for the fields that are relevant to the configuration of sending the notification.
@@ -97,6 +127,7 @@ type Notification struct {
 	Delivery map[string]interface{} `yaml:"delivery"`
 	Params   map[string]string      `yaml:"params"`
 	Template *Template              `yaml:"template"`
+	Schedule *ScheduleConfig        `yaml:"schedule"`
 }
 
 type Template struct {
@@ -110,6 +141,27 @@ type Template struct {
 type TemplateView struct {
 	Build  *BuildView        `json:"Build"`
 	Params map[string]string `json:"Params"`
+	// Event is the metadata of the event that delivered the Build (see EventMetadataFromContext).
+	Event *EventMetadata `json:"Event,omitempty"`
+	// RateLimit summarizes the notifications that were held back by the rate limit (see RateLimitSummaryFromContext).
+	RateLimit *RateLimitSummary `json:"RateLimit,omitempty"`
+	// Transition is how the Build's status compares to the previous Build's (see TransitionFromContext).
+	Transition *Transition `json:"Transition,omitempty"`
+	// Digest describes the notifications that were deferred during quiet hours (see DigestFromContext).
+	Digest *Digest `json:"Digest,omitempty"`
+}
+
+// NewTemplateView returns the TemplateView of the given Build and params, with the metadata that the context carries
+// about its event, rate limit, transition, and digest.
+func NewTemplateView(ctx context.Context, build *cbpb.Build, params map[string]string) *TemplateView {
+	return &TemplateView{
+		Build:      &BuildView{Build: build},
+		Params:     params,
+		Event:      EventMetadataFromContext(ctx),
+		RateLimit:  RateLimitSummaryFromContext(ctx),
+		Transition: TransitionFromContext(ctx),
+		Digest:     DigestFromContext(ctx),
+	}
 }
 
 // BuildView is the data container that contains the build
@@ -130,14 +182,15 @@ type Secret struct {
 
 // Copied from https://cloud.google.com/run/docs/tutorials/pubsub#looking_at_the_code.
 type pubSubPushMessage struct {
-	Data        []byte `json:"data,omitempty"`
-	ID          string `json:"id"`
-	PublishTime string `json:"publishTime"`
+	Data        []byte            `json:"data,omitempty"`
+	Attributes  map[string]string `json:"attributes,omitempty"`
+	ID          string            `json:"id"`
+	PublishTime string            `json:"publishTime"`
 }
 
 type pubSubPushWrapper struct {
-	Message      pubSubPushMessage
-	Subscription string `json:"subscription"`
+	Message      pubSubPushMessage `json:"message"`
+	Subscription string            `json:"subscription"`
 }
 
 // Notifier is the interface type that users should implement for usage in Cloud Build notifiers.
@@ -161,23 +214,43 @@ type EventFilter interface {
 // notifications should be sent for a given Pub/Sub message.
 type CELPredicate struct {
 	prg cel.Program
+	// env is a snapshot of the environment variables, which filters see as `env`.
+	env map[string]string
 }
 
 // Apply returns true iff the underlying CEL program returns true for the given Build.
-func (c *CELPredicate) Apply(_ context.Context, build *cbpb.Build) bool {
-	out, _, err := c.prg.Eval(map[string]interface{}{"build": build})
+func (c *CELPredicate) Apply(ctx context.Context, build *cbpb.Build) bool {
+	_, span := StartSpan(ctx, "CELPredicate.Apply")
+	match, err := c.eval(ctx, build)
 	if err != nil {
-		log.Errorf("failed to evaluate the CEL filter: %v", err)
+		EndSpan(span, err)
+		filterEvaluations.WithLabelValues(filterError).Inc()
+		Errorf(ctx, "%v", err)
 		return false
 	}
 
+	span.SetAttributes(attribute.Bool("match", match))
+	EndSpan(span, nil)
+	if match {
+		filterEvaluations.WithLabelValues(filterMatch).Inc()
+	} else {
+		filterEvaluations.WithLabelValues(filterMiss).Inc()
+	}
+	return match
+}
+
+// eval runs the CEL program on the given Build without recording any metrics or spans.
+func (c *CELPredicate) eval(ctx context.Context, build *cbpb.Build) (bool, error) {
+	out, _, err := c.prg.Eval(celVars(ctx, build, c.env))
+	if err != nil {
+		return false, fmt.Errorf("failed to evaluate the CEL filter: %w", err)
+	}
+
 	match, ok := out.Value().(bool)
 	if !ok {
-		log.Errorf("failed to convert output %v of CEL filter program to a boolean: %v", out, err)
-		return false
+		return false, fmt.Errorf("failed to convert output %v of CEL filter program to a boolean", out)
 	}
-
-	return match
+	return match, nil
 }
 
 // Main is a function that can be called by `main()` functions in notifier binaries.
@@ -188,122 +261,390 @@ func Main(notifier Notifier) error {
 	if !flag.Parsed() {
 		flag.Parse()
 	}
+	if err := configureLogging(); err != nil {
+		return err
+	}
 	if *smoketest {
-		log.V(0).Infof("notifier smoketest: %T", notifier)
+		Infof(ctx, "notifier smoketest: %T", notifier)
 		return nil
 	}
 
 	if *setupCheck {
-		log.V(2).Info("starting setup check")
-		cfg, err := decodeConfig(os.Stdin)
-		if err != nil {
-			return fmt.Errorf("failed to decode YAML config from stdin: %w", err)
-		}
+		sc := new(lazyGCSClient)
+		defer sc.Close()
+		return runSetupCheck(ctx, notifier, os.Stdin, &configSource{grf: &actualGCSReaderFactory{sc}}, *setupCheckBuilds, os.Stdout)
+	}
 
-		if out, err := yaml.Marshal(cfg); err != nil {
-			log.Warningf("failed to re-encode config YAML: %v", err)
-		} else {
-			log.V(2).Infof("got re-encoded YAML from stdin:\n%s", string(out))
+	if *renderBuild != "" {
+		cfgPath := *renderConfig
+		if cfgPath == "" {
+			cfgPath, _ = GetEnv("CONFIG_PATH")
 		}
-
-		if err := validateConfig(cfg); err != nil {
-			return fmt.Errorf("failed to validate config during setup check: %w", err)
+		if cfgPath == "" {
+			return errors.New("expected -render_config or CONFIG_PATH to be non-empty for -render")
 		}
+		return render(ctx, notifier, cfgPath, *renderTemplate, *renderBuild, os.Stdout)
+	}
 
-		br, err := newResolver(cfg)
-		if err != nil {
-			return fmt.Errorf("failed to create BindingResolver during setup check: %w", err)
-		}
+	shutdownTracing, err := setUpTracing(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to set up tracing: %w", err)
+	}
+	defer shutdownTracing(context.Background())
 
-		if err := notifier.SetUp(ctx, cfg, "", new(setupCheckSecretGetter), br); err != nil {
-			return fmt.Errorf("failed to run notifier.SetUp during setup check: %w", err)
-		}
+	// Cloud Run sends a SIGTERM before it stops an instance, after which in-flight notifications are drained.
+	sigCtx, stop := signal.NotifyContext(ctx, syscall.SIGTERM, os.Interrupt)
+	defer stop()
 
-		log.V(2).Infof("setup check successful")
-		return nil
+	cfgPath, hasPath := GetEnv("CONFIG_PATH")
+	cfgYAML, hasYAML := GetEnv("CONFIG_YAML")
+	if hasPath == hasYAML {
+		return errors.New("expected exactly one of CONFIG_PATH or CONFIG_YAML to be non-empty")
 	}
 
-	cfgPath, ok := GetEnv("CONFIG_PATH")
-	if !ok {
-		return errors.New("expected CONFIG_PATH to be non-empty")
-	}
+	// The GCS client is only created once a `gs://` config, template, or dead-letter URI needs it.
+	sc := new(lazyGCSClient)
+	defer sc.Close()
 
-	sc, err := storage.NewClient(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to create new GCS client: %w", err)
+	// Like the GCS client, the Secret Manager client is only created once a Secret Manager secret needs it.
+	sm := new(actualSecretManager)
+	defer sm.Close()
+
+	secretTTL := defaultSecretCacheTTL
+	if st, ok := GetEnv("SECRET_CACHE_TTL"); ok {
+		var err error
+		secretTTL, err = time.ParseDuration(st)
+		if err != nil {
+			return fmt.Errorf("expected SECRET_CACHE_TTL %q to be a duration: %w", st, err)
+		}
 	}
-	defer sc.Close()
 
-	smc, err := secretmanager.NewClient(ctx)
+	drainTimeout, err := drainTimeoutFromEnv()
 	if err != nil {
-		return fmt.Errorf("failed to create new SecretManager client: %w", err)
+		return err
 	}
-	defer smc.Close()
 
-	cfg, err := getGCSConfig(ctx, &actualGCSReaderFactory{sc}, cfgPath)
+	// Dry-run mode must be on before the notifier is set up, since some notifiers pick their delivery client then.
+	dr, _ := GetEnv("DRY_RUN")
+	ds, err := newDeliverySink(dr)
 	if err != nil {
-		return fmt.Errorf("failed to get config from GCS: %w", err)
+		return err
 	}
-
-	if err := validateConfig(cfg); err != nil {
-		return fmt.Errorf("got invalid config from path %q: %w", cfgPath, err)
+	if ds != nil {
+		dryRun = ds
+		Warningf(ctx, "running in dry-run mode: notifications are captured and not delivered")
 	}
-	log.V(2).Infof("got config from GCS (%q): %+v\n", cfgPath, cfg)
 
-	tmpl, err := parseTemplate(ctx, cfg.Spec.Notification.Template, &actualGCSReaderFactory{sc})
-	if err != nil {
-		return fmt.Errorf("failed to parse template from notiifer spec %q: %w", cfg.Spec.Notification.Template, err)
+	cl := &configLoader{
+		notifier: notifier,
+		path:     cfgPath,
+		inline:   cfgYAML,
+		src:      &configSource{grf: &actualGCSReaderFactory{sc}},
+		sg:       newCachingSecretGetter(newSecretDispatcher(sm), secretTTL),
+		rl:       newRateLimiter(),
+		sch:      newScheduler(),
 	}
-
-	sm := &actualSecretManager{client: smc}
-
-	br, err := newResolver(cfg)
+	ld, err := cl.load(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to construct a binding resolver: %v", err)
+		return err
 	}
+	cfg := ld.cfg
 
-	if err := notifier.SetUp(ctx, cfg, tmpl, sm, br); err != nil {
-		return fmt.Errorf("failed to call SetUp on notifier: %w", err)
+	routed := newReloadingNotifier(cl, ld)
+	if ri, ok := GetEnv("CONFIG_RELOAD_INTERVAL"); ok {
+		interval, err := time.ParseDuration(ri)
+		if err != nil || interval <= 0 {
+			return fmt.Errorf("expected CONFIG_RELOAD_INTERVAL %q to be a positive duration", ri)
+		}
+		go routed.watch(sigCtx, interval)
 	}
 
 	_, ignoreBadMessages := GetEnv("IGNORE_BAD_MESSAGES")
+	rp := &receiverParams{
+		ignoreBadMessages: ignoreBadMessages,
+		retry:             cfg.Spec.Retry,
+		notifierType:      notifierType(notifier),
+		drainer:           new(drainer),
+	}
+	// Once the in-flight notifications are done, the held back rate limit summaries and quiet hours digests are sent
+	// before shutting down.
+	rp.drainer.onDrain(cl.rl.drain)
+	rp.drainer.onDrain(cl.sch.drain)
+	if cfg.Spec.DeadLetter != nil {
+		dls, err := newDeadLetterSink(cfg.Spec.DeadLetter, &actualGCSWriterFactory{sc})
+		if err != nil {
+			return fmt.Errorf("failed to create dead-letter sink: %w", err)
+		}
+		rp.deadLetter = dls
+	}
+	if cfg.Spec.Dedupe != nil {
+		ds, err := newDedupeStore(cfg.Spec.Dedupe)
+		if err != nil {
+			return fmt.Errorf("failed to create dedupe store: %w", err)
+		}
+		rp.dedupe = ds
+	}
+	if cfg.Spec.PushAuth != nil {
+		ov, err := newOIDCVerifier(ctx, cfg.Spec.PushAuth)
+		if err != nil {
+			return err
+		}
+		rp.pushAuth = ov
+	}
+	if cfg.Spec.Transitions != nil {
+		ss, err := newStatusStore(cfg.Spec.Transitions)
+		if err != nil {
+			return fmt.Errorf("failed to create transitions store: %w", err)
+		}
+		rp.statuses = ss
+	}
 
-	log.V(2).Infoln("starting HTTP server...")
+	// In pull mode, messages are pulled from a subscription and the HTTP server only serves the auxiliary endpoints.
+	errc := make(chan error, 2)
+	mux := http.NewServeMux()
+	switch mode, _ := GetEnv("MODE"); mode {
+	case "", "push":
+		// Our Pub/Sub push receiver.
+		mux.HandleFunc("/", newReceiver(routed, rp))
+	case "pull":
+		ps, err := pullSettingsFromEnv()
+		if err != nil {
+			return err
+		}
+		psc, err := pubsub.NewClient(ctx, ps.project)
+		if err != nil {
+			return fmt.Errorf("failed to create new PubSub client: %w", err)
+		}
+		defer psc.Close()
+
+		// The subscriber itself counts as in-flight work, so that draining waits for Receive to return (which it does
+		// once its callbacks have).
+		rp.drainer.acquire()
+		go func() {
+			defer rp.drainer.release()
+			if err := runPull(sigCtx, psc, routed, rp, ps); err != nil {
+				errc <- fmt.Errorf("failed to pull PubSub messages: %w", err)
+				return
+			}
+			if sigCtx.Err() == nil {
+				errc <- errors.New("stopped pulling PubSub messages")
+			}
+		}()
+	default:
+		return fmt.Errorf("expected MODE %q to be one of `push` or `pull`", mode)
+	}
 
-	// Our Pub/Sub push receiver.
-	http.HandleFunc("/", newReceiver(notifier, &receiverParams{ignoreBadMessages}))
+	Debugf(ctx, "starting HTTP server...")
 
-	// An auxilliary, healthz-style receiver.
+	// Prometheus metrics about received messages and their notifications.
+	mux.Handle("/metrics", metricsHandler())
+
+	// An auxilliary receiver that describes the notifier and its config as JSON.
 	// You can call this endpoint using the curl command here:
 	// https://cloud.google.com/run/docs/triggering/https-request#creating_private_services.
-	startTime := time.Now()
-	http.HandleFunc("/helloz", func(w http.ResponseWriter, _ *http.Request) {
-		fmt.Fprintf(w, "Greetings from a Google Cloud Build notifier: %T!\nStart Time: %s\nCurrent Time: %s\n",
-			notifier, startTime.Format(time.RFC1123), time.Now().Format(time.RFC1123))
-	})
+	mux.HandleFunc("/helloz", hellozHandler(notifier, routed, time.Now()))
+
+	// Liveness and readiness checks.
+	mux.HandleFunc("/healthz", healthzHandler())
+	mux.HandleFunc("/readyz", readyzHandler(routed, rp.drainer))
 
 	var port string
 	if p, ok := GetEnv("PORT"); ok {
 		port = p
 	} else {
-		log.Warningf("PORT environment variable was not present, using %s instead", defaultHTTPPort)
+		Warningf(ctx, "PORT environment variable was not present, using %s instead", defaultHTTPPort)
 		port = defaultHTTPPort
 	}
 
-	// Block on the HTTP's health.
-	return http.ListenAndServe(":"+port, nil)
+	ln, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		return fmt.Errorf("failed to listen on port %s: %w", port, err)
+	}
+
+	// Block on the health of the HTTP server (and of the subscriber in pull mode) until we are told to shut down.
+	// The deferred clients are closed only after the in-flight notifications have been drained.
+	return serve(sigCtx, &http.Server{Handler: mux}, ln, errc, rp.drainer, drainTimeout)
+}
+
+// setUpNotifier calls SetUp on the given notifier for every route in the Config and returns the Notifier that should
+// receive Builds. A Config with a single route sets up the given notifier itself; a Config with multiple routes sets up
+// a copy of it per route and returns a Notifier that fans Builds out to all of them.
+// The i-th template is the (already parsed) template of the i-th route.
+// If the Config has a rate limit and the given rateLimiter is non-nil, every route's notifier is rate limited with it.
+func setUpNotifier(ctx context.Context, notifier Notifier, cfg *Config, tmpls []string, sg SecretGetter, rl *rateLimiter, sch *scheduler) (Notifier, error) {
+	routes := cfg.Spec.Routes()
+	if len(tmpls) != len(routes) {
+		return nil, fmt.Errorf("got %d templates for %d notification routes", len(tmpls), len(routes))
+	}
+
+	mn := &multiNotifier{}
+	for i, route := range routes {
+		n := notifier
+		if len(routes) > 1 {
+			n = cloneNotifier(notifier)
+		}
+		rc := routeConfig(cfg, route)
+
+		br, err := newResolver(rc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to construct a binding resolver for notification route %d: %w", i, err)
+		}
+
+		if err := n.SetUp(ctx, rc, tmpls[i], sg, br); err != nil {
+			return nil, fmt.Errorf("failed to call SetUp on notifier for notification route %d: %w", i, err)
+		}
+
+		if rl != nil && cfg.Spec.RateLimit != nil {
+			filter, err := MakeCELPredicate(route.Filter)
+			if err != nil {
+				return nil, fmt.Errorf("failed to make the rate limit filter for notification route %d: %w", i, err)
+			}
+			n = &rateLimitedNotifier{notifier: n, filter: filter, cfg: cfg.Spec.RateLimit, rl: rl, routeKey: fmt.Sprintf("route/%d", i)}
+		}
+		// Deferred notifications are only rate limited once their digest is sent.
+		if sch != nil && route.Schedule != nil {
+			filter, err := MakeCELPredicate(route.Filter)
+			if err != nil {
+				return nil, fmt.Errorf("failed to make the schedule filter for notification route %d: %w", i, err)
+			}
+			s, err := parseSchedule(route.Schedule)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse the schedule of notification route %d: %w", i, err)
+			}
+			n = &scheduledNotifier{notifier: n, filter: filter, schedule: s, sch: sch, routeKey: fmt.Sprintf("route/%d", i)}
+		}
+		// The params are resolved first, so that every filter of the route can refer to them.
+		if len(route.Params) > 0 {
+			n = &paramsNotifier{notifier: n, br: br, sg: sg}
+		}
+		mn.routes = append(mn.routes, n)
+	}
+
+	if len(mn.routes) == 1 {
+		return mn.routes[0], nil
+	}
+	return mn, nil
+}
+
+// parseTemplates returns the parsed template of every route in the Config.
+// Relative template URIs are resolved against the given base, which is the location of the Config (if any).
+func parseTemplates(ctx context.Context, cfg *Config, src *configSource, base string) ([]string, error) {
+	var tmpls []string
+	for i, route := range cfg.Spec.Routes() {
+		t, err := parseTemplate(ctx, route.Template, src, base)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse template from notification route %d (%+v): %w", i, route.Template, err)
+		}
+		tmpls = append(tmpls, t)
+	}
+	return tmpls, nil
+}
+
+// routeConfig returns a copy of the given Config whose Spec only contains the given route as its `notification`.
+// This lets notifiers keep reading `cfg.Spec.Notification` regardless of how many routes the Config has.
+func routeConfig(cfg *Config, route *Notification) *Config {
+	spec := *cfg.Spec
+	spec.Notification = route
+	spec.Notifications = nil
+
+	rc := *cfg
+	rc.Spec = &spec
+	return &rc
+}
+
+// cloneNotifier returns a shallow copy of the given (not yet set up) notifier, so that every route can be set up on its
+// own instance while keeping any fields (e.g. client factories) that were populated before calling Main.
+func cloneNotifier(notifier Notifier) Notifier {
+	v := reflect.ValueOf(notifier)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return notifier
+	}
+	c := reflect.New(v.Elem().Type())
+	c.Elem().Set(v.Elem())
+	return c.Interface().(Notifier)
+}
+
+// multiNotifier is a Notifier that sends every Build to the notifiers of all routes in a Config.
+// Each route's notifier applies its own filter.
+type multiNotifier struct {
+	routes []Notifier
+}
+
+// SetUp is a no-op since every route's notifier is set up individually by setUpNotifier.
+func (m *multiNotifier) SetUp(_ context.Context, _ *Config, _ string, _ SecretGetter, _ BindingResolver) error {
+	return nil
+}
+
+// SendNotification sends the given Build to every route and returns the joined routeErrors of the routes that failed.
+// Routes that the context records as already delivered to (see routeDeliveries), or that the context excludes (see
+// withOnlyRoutes), are skipped.
+func (m *multiNotifier) SendNotification(ctx context.Context, build *cbpb.Build) error {
+	rd := routeDeliveriesFromContext(ctx)
+	only, _ := ctx.Value(onlyRoutesKey{}).(map[int]bool)
+	var errs []error
+	for i, n := range m.routes {
+		if only != nil && !only[i] {
+			continue
+		}
+		if rd.done(ctx, i) {
+			Debugf(ctx, "not sending notification route %d since it was already delivered", i)
+			continue
+		}
+		// Notifiers may modify the Build (e.g. adding UTM params to the log URL), so give each route its own copy.
+		if err := n.SendNotification(ctx, proto.Clone(build).(*cbpb.Build)); err != nil {
+			errs = append(errs, &routeError{route: i, err: err})
+			continue
+		}
+		rd.markDone(ctx, i)
+	}
+	return errors.Join(errs...)
+}
+
+// routeError is the error of a single route of a multiNotifier.
+type routeError struct {
+	route int
+	err   error
+}
+
+func (e *routeError) Error() string {
+	return fmt.Sprintf("notification route %d failed: %v", e.route, e.err)
+}
+
+func (e *routeError) Unwrap() error {
+	return e.err
+}
+
+type onlyRoutesKey struct{}
+
+// withOnlyRoutes returns a context in which a multiNotifier only sends to the given routes.
+func withOnlyRoutes(ctx context.Context, routes map[int]bool) context.Context {
+	return context.WithValue(ctx, onlyRoutesKey{}, routes)
+}
+
+// CheckHealth checks every route that implements HealthChecker and returns the joined errors of the unhealthy ones.
+func (m *multiNotifier) CheckHealth(ctx context.Context) error {
+	var errs []error
+	for i, n := range m.routes {
+		if hc, ok := n.(HealthChecker); ok {
+			if err := hc.CheckHealth(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("notification route %d is unhealthy: %w", i, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
 }
 
-func parseTemplate(ctx context.Context, tmpl *Template, grf gcsReaderFactory) (string, error) {
+func parseTemplate(ctx context.Context, tmpl *Template, src *configSource, base string) (string, error) {
 	templateString := ""
 	if tmpl != nil {
 		if _, ok := allowedTemplateTypes[tmpl.Type]; !ok {
 			return "", fmt.Errorf("got invalid Template Type: %v", tmpl.Type)
 		}
 		if tmpl.URI != "" {
-			parsed, err := getGCSTemplate(ctx, grf, tmpl.URI)
+			uri := resolveURI(base, tmpl.URI)
+			parsed, err := src.getTemplate(ctx, uri)
 			if err != nil {
-				return "", fmt.Errorf("failed to get template from GCS: %w", err)
+				return "", fmt.Errorf("failed to get template from %q: %w", uri, err)
 			}
 			templateString = parsed
 		} else {
@@ -322,21 +663,56 @@ type gcsReaderFactory interface {
 }
 
 type actualGCSReaderFactory struct {
-	client *storage.Client
+	client *lazyGCSClient
 }
 
 func (a *actualGCSReaderFactory) NewReader(ctx context.Context, bucket, object string) (io.ReadCloser, error) {
-	return a.client.Bucket(bucket).Object(object).NewReader(ctx)
+	c, err := a.client.get()
+	if err != nil {
+		return nil, err
+	}
+	return c.Bucket(bucket).Object(object).NewReader(ctx)
 }
 
+// actualSecretManager gets secrets from GCP Secret Manager. Its client is created on first use.
 type actualSecretManager struct {
+	mtx    sync.Mutex
 	client *secretmanager.Client
-	// TODO(ljr): Do we want any sort of timed cache here?
+}
+
+func (a *actualSecretManager) getClient(ctx context.Context) (*secretmanager.Client, error) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	if a.client == nil {
+		c, err := secretmanager.NewClient(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create new SecretManager client: %w", err)
+		}
+		a.client = c
+	}
+	return a.client, nil
+}
+
+// Close closes the Secret Manager client if it was ever created.
+func (a *actualSecretManager) Close() error {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	if a.client == nil {
+		return nil
+	}
+	return a.client.Close()
 }
 
 func (a *actualSecretManager) GetSecret(ctx context.Context, name string) (string, error) {
+	client, err := a.getClient(ctx)
+	if err != nil {
+		return "", err
+	}
+
 	// See https://github.com/GoogleCloudPlatform/golang-samples/blob/master/secretmanager/access_secret_version.go# for an example usage.
-	res, err := a.client.AccessSecretVersion(ctx, &smpb.AccessSecretVersionRequest{Name: name})
+	res, err := client.AccessSecretVersion(ctx, &smpb.AccessSecretVersionRequest{Name: name})
 	if err != nil {
 		return "", fmt.Errorf("failed to get secret named %q: %w", name, err)
 	}
@@ -364,7 +740,7 @@ func getGCSConfig(ctx context.Context, grf gcsReaderFactory, path string) (*Conf
 	// }
 
 	// split := strings.SplitN(path, "/", 2)
-	// log.V(2).Infof("got path split: %+v", split)
+	// Debugf(ctx, "got path split: %+v", split)
 	// if len(split) != 2 {
 	// 	return nil, fmt.Errorf("path has incorrect format (expected form: `[gs://]bucket/path/to/object`): %q => %s", path, strings.Join(split, ", "))
 	// }
@@ -397,7 +773,7 @@ func getGCSTemplate(ctx context.Context, grf gcsReaderFactory, path string) (str
 	}
 
 	split := strings.SplitN(path, "/", 2)
-	log.V(2).Infof("got path split: %+v", split)
+	Debugf(ctx, "got path split: %+v", split)
 	if len(split) != 2 {
 		return "", fmt.Errorf("path has incorrect format (expected form: `[gs://]bucket/path/to/object`): %q => %s", path, strings.Join(split, ", "))
 	}
@@ -435,6 +811,7 @@ func decodeTemplate(r io.Reader) (string, error) {
 // validateConfig checks the following (or errors):
 // - apiVersion is one of allowedYAMLAPIVersions.
 // - user substitution names match the subNamePattern regexp.
+// - exactly one of spec.notification or (a non-empty) spec.notifications is present.
 func validateConfig(cfg *Config) error {
 	if allowed := allowedYAMLAPIVersions[cfg.APIVersion]; !allowed {
 		return fmt.Errorf("expected `apiVersion` %q to be one of the following:\n%v",
@@ -445,37 +822,117 @@ func validateConfig(cfg *Config) error {
 		return errors.New("expected config.spec to be present")
 	}
 
-	if cfg.Spec.Notification == nil {
-		return errors.New("expected config.spec.notification to be present")
+	if cfg.Spec.Notification != nil && len(cfg.Spec.Notifications) > 0 {
+		return errors.New("expected only one of config.spec.notification and config.spec.notifications to be present")
+	}
+
+	if len(cfg.Spec.Routes()) == 0 {
+		return errors.New("expected config.spec.notification or config.spec.notifications to be present")
+	}
+
+	for i, n := range cfg.Spec.Notifications {
+		if n == nil {
+			return fmt.Errorf("expected config.spec.notifications[%d] to be non-empty", i)
+		}
+	}
+
+	for i, n := range cfg.Spec.Routes() {
+		if n.Schedule != nil {
+			if _, err := parseSchedule(n.Schedule); err != nil {
+				return fmt.Errorf("got invalid schedule for notification route %d: %w", i, err)
+			}
+		}
+	}
+
+	if cfg.Spec.Retry != nil {
+		if err := validateRetryPolicy(cfg.Spec.Retry); err != nil {
+			return fmt.Errorf("got invalid config.spec.retry: %w", err)
+		}
+	}
+
+	if cfg.Spec.DeadLetter != nil {
+		if _, err := newDeadLetterSink(cfg.Spec.DeadLetter, nil); err != nil {
+			return fmt.Errorf("got invalid config.spec.deadLetter: %w", err)
+		}
+	}
+
+	if cfg.Spec.Dedupe != nil {
+		if err := validateDedupeConfig(cfg.Spec.Dedupe); err != nil {
+			return fmt.Errorf("got invalid config.spec.dedupe: %w", err)
+		}
+	}
+
+	if cfg.Spec.PushAuth != nil {
+		if err := validatePushAuthConfig(cfg.Spec.PushAuth); err != nil {
+			return fmt.Errorf("got invalid config.spec.pushAuth: %w", err)
+		}
+	}
+
+	if cfg.Spec.RateLimit != nil {
+		if err := validateRateLimitConfig(cfg.Spec.RateLimit); err != nil {
+			return fmt.Errorf("got invalid config.spec.rateLimit: %w", err)
+		}
+	}
+
+	if cfg.Spec.Transitions != nil {
+		if err := validateTransitionsConfig(cfg.Spec.Transitions); err != nil {
+			return fmt.Errorf("got invalid config.spec.transitions: %w", err)
+		}
 	}
 
 	return nil
 }
 
 func validateTemplate(s string) error {
-	_, err := template.New("").Funcs(template.FuncMap{
-		"replace": func(s, old, new string) string {
-			return strings.ReplaceAll(s, old, new)
-		},
-	}).Parse(s)
+	_, err := template.New("").Funcs(TemplateFuncs()).Parse(s)
 
 	return err
 }
 
-// MakeCELPredicate returns a CELPredicate for the given filter string of CEL code.
-func MakeCELPredicate(filter string) (*CELPredicate, error) {
-	env, err := cel.NewEnv(
+// newCELEnv returns the CEL environment that filters and `cel(...)` params are compiled in.
+func newCELEnv() (*cel.Env, error) {
+	reg, err := types.NewRegistry(new(cbpb.Build))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create a CEL type registry: %w", err)
+	}
+	opts := []cel.EnvOption{
+		cel.CustomTypeProvider(reg),
+		cel.CustomTypeAdapter(reg),
 		// Declare the `build` variable for useage in CEL programs.
 		cel.Declarations(decls.NewIdent("build", decls.NewObjectType(cloudBuildProtoPkg+".Build"), nil)),
+		// Declare the status of the previous Build of the same trigger and branch and the kind of the transition from
+		// it (see Transition).
+		cel.Declarations(
+			decls.NewIdent("previous_status", decls.Int, nil),
+			decls.NewIdent("transition", decls.String, nil),
+		),
+		// Declare the resolved params of the route, the environment variables, and the metadata (including the
+		// attributes) of the Pub/Sub message or CloudEvent that delivered the Build.
+		cel.Declarations(
+			decls.NewIdent("params", decls.NewMapType(decls.String, decls.String), nil),
+			decls.NewIdent("env", decls.NewMapType(decls.String, decls.String), nil),
+			decls.NewIdent("message", decls.NewMapType(decls.String, decls.Dyn), nil),
+		),
 		// Register the `Build` type in the environment.
 		cel.Types(new(cbpb.Build)),
 		// `Container` is necessary for better (enum) scoping
 		// (i.e with this, we don't need to use the fully qualified proto path in our programs).
 		cel.Container(cloudBuildProtoPkg),
-	)
+	}
+	// Declare the functions and macros of the filter library (see celfuncs.go).
+	env, err := cel.NewEnv(append(opts, celFunctions(reg)...)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create a CEL env: %w", err)
 	}
+	return env, nil
+}
+
+// MakeCELPredicate returns a CELPredicate for the given filter string of CEL code.
+func MakeCELPredicate(filter string) (*CELPredicate, error) {
+	env, err := newCELEnv()
+	if err != nil {
+		return nil, err
+	}
 
 	ast, issues := env.Compile(filter)
 	if issues != nil && issues.Err() != nil {
@@ -491,75 +948,224 @@ func MakeCELPredicate(filter string) (*CELPredicate, error) {
 		return nil, fmt.Errorf("failed to create CEL program from filter %q: %w", filter, err)
 	}
 
-	return &CELPredicate{prg}, nil
+	return &CELPredicate{prg: prg, env: celEnv()}, nil
 }
 
 // GetEnv fetches, logs, and returns the given environment variable. The returned boolean is true iff the value is non-empty.
 func GetEnv(name string) (string, bool) {
 	val := os.Getenv(name)
 	if val == "" {
-		log.V(2).Infof("env var %q is empty", name)
+		Debugf(context.Background(), "env var %q is empty", name)
 	} else {
-		log.V(2).Infof("env var %q is %q", name, val)
+		Debugf(context.Background(), "env var %q is %q", name, val)
 	}
 	return val, val != ""
 }
 
 type receiverParams struct {
 	ignoreBadMessages bool
+	// retry is the policy for retrying failed SendNotification calls. If nil, only one attempt is made.
+	retry *RetryPolicy
+	// deadLetter receives messages that failed all attempts, after which they are acked. If nil, they are nacked.
+	deadLetter deadLetterSink
+	// dedupe remembers completed deliveries so that redelivered messages are skipped. If nil, nothing is skipped.
+	dedupe dedupeStore
+	// pushAuth verifies the OIDC token of pushed requests. If nil, requests are not authenticated.
+	pushAuth *oidcVerifier
+	// statuses remembers the last status of every trigger and branch to find the Transition of every Build. If nil,
+	// transitions are not tracked.
+	statuses statusStore
+	// notifierType labels the SendNotification metrics.
+	notifierType string
+	// drainer tracks the messages that are being handled so that shutdown can wait for them. If nil, they are not
+	// tracked.
+	drainer *drainer
 }
 
 // newReceiver returns a Pub/Sub push HTTP receiving http.HandlerFunc that calls the given notifier.
 func newReceiver(notifier Notifier, params *receiverParams) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		ctx := r.Context()
+	return func(rw http.ResponseWriter, r *http.Request) {
+		// Continue the trace of the pushing service (if any).
+		ctx, span := otel.Tracer(tracerName).Start(tracePropagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header)),
+			"ReceiveMessage", trace.WithSpanKind(trace.SpanKindServer))
+		w := &statusRecorder{ResponseWriter: rw, status: http.StatusOK}
+		defer w.endSpan(span)
+
+		if params.pushAuth != nil {
+			if err := params.pushAuth.verifyRequest(r); err != nil {
+				Warningf(ctx, "rejecting unauthenticated request: %v", err)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+
 		var pspw pubSubPushWrapper
 		body, err := ioutil.ReadAll(r.Body)
 		if err != nil {
-			log.Errorf("failed to read request message: %v", err)
+			Errorf(ctx, "failed to read request message: %v", err)
 			http.Error(w, "Bad request body", http.StatusBadRequest)
 			return
 		}
 
-		if err := json.Unmarshal(body, &pspw); err != nil {
-			log.Errorf("failed to unmarshal body %q: %v", body, err)
-			http.Error(w, "Bad pubsub.Message JSON", http.StatusBadRequest)
-			return
+		// Messages that are routed through Eventarc arrive as CloudEvents rather than Pub/Sub push messages.
+		msg := &pspw.Message
+		var em *EventMetadata
+		if isCloudEvent(r) {
+			msg, em, err = decodeCloudEvent(r.Header, body)
+			if err != nil {
+				Errorf(ctx, "failed to decode CloudEvent with body %q: %v", truncatePayload(body), err)
+				messagesReceived.WithLabelValues(messageDecodeError).Inc()
+				http.Error(w, "Bad CloudEvent", http.StatusBadRequest)
+				return
+			}
+			Debugf(ctx, "got CloudEvent with ID %q from source %q", em.ID, em.Source)
+			span.SetAttributes(attribute.String("cloudevents.event_id", em.ID), attribute.String("cloudevents.event_source", em.Source))
+		} else {
+			if err := json.Unmarshal(body, &pspw); err != nil {
+				Errorf(ctx, "failed to unmarshal body %q: %v", truncatePayload(body), err)
+				messagesReceived.WithLabelValues(messageDecodeError).Inc()
+				http.Error(w, "Bad pubsub.Message JSON", http.StatusBadRequest)
+				return
+			}
+			em = pubSubEventMetadata(pspw.Subscription, msg)
+			Debugf(ctx, "got PubSub message with ID %q from subscription %q", pspw.Message.ID, pspw.Subscription)
+			span.SetAttributes(attribute.String("messaging.message.id", pspw.Message.ID), attribute.String("messaging.subscription", pspw.Subscription))
+		}
+
+		switch handleMessage(withEventMetadata(ctx, em), notifier, params, msg, body) {
+		case badMessage:
+			http.Error(w, "Bad Cloud Build Pub/Sub data", http.StatusBadRequest)
+		case sendFailed:
+			http.Error(w, "failed to send notification", http.StatusInternalServerError)
+		case shuttingDown:
+			http.Error(w, "Shutting down", http.StatusServiceUnavailable)
+		}
+	}
+}
+
+// messageResult is the outcome of handling a Pub/Sub message.
+type messageResult int
+
+const (
+	// messageDone means that the message should be acked: its notification was sent, skipped, or dead-lettered.
+	messageDone messageResult = iota
+	// badMessage means that the message could not be decoded into a Build.
+	badMessage
+	// sendFailed means that the notification could not be sent (nor dead-lettered).
+	sendFailed
+	// shuttingDown means that the message was not handled since the notifier is shutting down.
+	shuttingDown
+)
+
+// handleMessage decodes the Build in the given Pub/Sub message and sends it with the given notifier.
+// The envelope is the raw message as it was received, which is what gets written to the dead-letter sink.
+func handleMessage(ctx context.Context, notifier Notifier, params *receiverParams, msg *pubSubPushMessage, envelope []byte) messageResult {
+	ctx = withMessageLogFields(ctx, msg.ID)
+	if params.drainer != nil {
+		if !params.drainer.acquire() {
+			Infof(ctx, "not handling PubSub message %q since the notifier is shutting down", msg.ID)
+			return shuttingDown
 		}
+		defer params.drainer.release()
+	}
 
-		log.V(2).Infof("got PubSub message with ID %q from subscription %q", pspw.Message.ID, pspw.Subscription)
+	build := new(cbpb.Build)
+	// Be as lenient as possible in unmarshalling.
+	// `Unmarshal` will fail if we get a payload with a field that is unknown to the current proto version unless `DiscardUnknown` is set.
+	uo := protojson.UnmarshalOptions{
+		AllowPartial:   true,
+		DiscardUnknown: true,
+	}
+	bv2 := protoadapt.MessageV2Of(build)
+	if err := uo.Unmarshal(msg.Data, bv2); err != nil {
+		if params.ignoreBadMessages {
+			messagesReceived.WithLabelValues(messageIgnored).Inc()
+			Warningf(ctx, "not attempting to handle unmarshal-able Pub/Sub message id=%q data=%q publishTime=%q which gave error: %v",
+				msg.ID, truncatePayload(msg.Data), msg.PublishTime, err)
+			return messageDone
+		}
 
-		build := new(cbpb.Build)
-		// Be as lenient as possible in unmarshalling.
-		// `Unmarshal` will fail if we get a payload with a field that is unknown to the current proto version unless `DiscardUnknown` is set.
-		uo := protojson.UnmarshalOptions{
-			AllowPartial:   true,
-			DiscardUnknown: true,
+		messagesReceived.WithLabelValues(messageDecodeError).Inc()
+		Errorf(ctx, "failed to unmarshal PubSub message id=%q data=%q publishTime=%q into a Build: %v",
+			msg.ID, truncatePayload(msg.Data), msg.PublishTime, err)
+		return badMessage
+	}
+	build = protoadapt.MessageV1Of(bv2).(*cbpb.Build)
+	ctx = withBuildLogFields(ctx, build)
+	messagesReceived.WithLabelValues(messageDecoded).Inc()
+
+	dks := dedupeKeys(msg.ID, build)
+	if params.dedupe != nil {
+		if key, ok := containsAny(ctx, params.dedupe, dks); ok {
+			messagesReceived.WithLabelValues(messageDuplicate).Inc()
+			Infof(ctx, "acking PubSub message %q without sending a notification since its delivery (%q) already completed", msg.ID, key)
+			return messageDone
 		}
-		bv2 := protoadapt.MessageV2Of(build)
-		if err := uo.Unmarshal(pspw.Message.Data, bv2); err != nil {
-			if params.ignoreBadMessages {
-				log.Warningf("not attempting to handle unmarshal-able Pub/Sub message id=%q data=%q publishTime=%q which gave error: %v",
-					pspw.Message.ID, string(pspw.Message.Data), pspw.Message.PublishTime, err)
-				return
-			}
+	}
 
-			log.Errorf("failed to unmarshal PubSub message id=%q data=%q publishTime=%q into a Build: %v",
-				pspw.Message.ID, string(pspw.Message.Data), pspw.Message.PublishTime, err)
-			http.Error(w, "Bad Cloud Build Pub/Sub data", http.StatusBadRequest)
-			return
+	if params.statuses != nil {
+		if t := transition(ctx, params.statuses, build); t != nil {
+			ctx = withTransition(ctx, t)
 		}
-		build = protoadapt.MessageV1Of(bv2).(*cbpb.Build)
+	}
 
-		log.V(2).Infof("got PubSub Build payload:\n%+v\nattempting to send notification", prototext.Format(build))
-		if err := notifier.SendNotification(ctx, build); err != nil {
-			log.Errorf("failed to run SendNotification: %v", err)
-			http.Error(w, "failed to send notification", http.StatusInternalServerError)
-			return
+	// With multiple routes, retries (and redeliveries, given a dedupe store) only go to the routes that failed.
+	ctx = withRouteDeliveries(ctx, params.dedupe, dks)
+
+	Debugf(ctx, "got PubSub Build payload %s, attempting to send notification", FormatBuild(build))
+	attempts, err := params.retry.do(ctx, func(ctx context.Context) error {
+		// Notifiers may modify the Build, so every attempt gets a fresh copy.
+		ctx, span := StartSpan(ctx, "SendNotification", attribute.String("notifier", params.notifierType))
+		start := time.Now()
+		err := notifier.SendNotification(ctx, proto.Clone(build).(*cbpb.Build))
+		EndSpan(span, err)
+		outcome := sendSuccess
+		if err != nil {
+			outcome = sendFailure
+		}
+		sendDuration.WithLabelValues(params.notifierType, outcome).Observe(time.Since(start).Seconds())
+		return err
+	})
+	if err != nil && errors.Is(err, ErrRateLimited) {
+		// Rather than being dead-lettered, a rate limited message is nacked so that Pub/Sub redelivers it later.
+		Warningf(ctx, "nacking PubSub message %q since it is rate limited: %v", msg.ID, err)
+		return sendFailed
+	}
+	if err != nil {
+		Errorf(ctx, "failed to run SendNotification after %d attempt(s): %v", attempts, err)
+		if params.deadLetter == nil {
+			return sendFailed
+		}
+
+		dl := &deadLetter{
+			MessageID: msg.ID,
+			Envelope:  envelope,
+			Error:     err.Error(),
+			Attempts:  attempts,
+			Time:      time.Now(),
+		}
+		if err := params.deadLetter.Write(ctx, dl); err != nil {
+			Errorf(ctx, "failed to write PubSub message %q to the dead-letter sink: %v", msg.ID, err)
+			return sendFailed
 		}
 
-		log.V(2).Infof("acking PubSub message %q with Build payload:\n%v", pspw.Message.ID, prototext.Format(build))
+		Warningf(ctx, "acking PubSub message %q after writing it to the dead-letter sink", msg.ID)
+		if params.statuses != nil {
+			recordStatus(ctx, params.statuses, build)
+		}
+		return messageDone
 	}
+
+	if params.dedupe != nil {
+		addAll(ctx, params.dedupe, dks)
+	}
+	// The status is only recorded once the message is acked, so that a redelivered message sees the same transition.
+	if params.statuses != nil {
+		recordStatus(ctx, params.statuses, build)
+	}
+
+	Debugf(ctx, "acking PubSub message %q with Build payload %s", msg.ID, FormatBuild(build))
+	return messageDone
 }
 
 // GetSecretRef is a helper function for getting a Secret's local reference name from the given config.
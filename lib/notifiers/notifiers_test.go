@@ -25,6 +25,8 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -644,6 +646,29 @@ func TestValidateConfig(t *testing.T) {
 				Spec:       &Spec{},
 			},
 			wantErr: true,
+		}, {
+			name: "valid spec.notifications",
+			cfg: &Config{
+				APIVersion: "cloud-build-notifiers/v1",
+				Spec:       &Spec{Notifications: []*Notification{{Filter: "a"}, {Filter: "b"}}},
+			},
+		}, {
+			name: "both spec.notification and spec.notifications",
+			cfg: &Config{
+				APIVersion: "cloud-build-notifiers/v1",
+				Spec: &Spec{
+					Notification:  &Notification{},
+					Notifications: []*Notification{{}},
+				},
+			},
+			wantErr: true,
+		}, {
+			name: "empty route in spec.notifications",
+			cfg: &Config{
+				APIVersion: "cloud-build-notifiers/v1",
+				Spec:       &Spec{Notifications: []*Notification{{}, nil}},
+			},
+			wantErr: true,
 		},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
@@ -664,6 +689,98 @@ func TestValidateConfig(t *testing.T) {
 	}
 }
 
+// routeRecordingNotifier records the filter of the route it was set up with alongside every Build that it is sent.
+type routeRecordingNotifier struct {
+	filter string
+	sent   *[]string // Shared between copies of the notifier.
+}
+
+func (r *routeRecordingNotifier) SetUp(_ context.Context, cfg *Config, _ string, _ SecretGetter, _ BindingResolver) error {
+	r.filter = cfg.Spec.Notification.Filter
+	return nil
+}
+
+func (r *routeRecordingNotifier) SendNotification(_ context.Context, build *cbpb.Build) error {
+	*r.sent = append(*r.sent, r.filter+"/"+build.Id)
+	return nil
+}
+
+func TestSetUpNotifierRoutes(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		name     string
+		spec     *Spec
+		wantSent []string
+	}{
+		{
+			name:     "single notification",
+			spec:     &Spec{Notification: &Notification{Filter: "only"}},
+			wantSent: []string{"only/some-build"},
+		}, {
+			name:     "multiple notifications",
+			spec:     &Spec{Notifications: []*Notification{{Filter: "oncall"}, {Filter: "deploys"}}},
+			wantSent: []string{"oncall/some-build", "deploys/some-build"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var sent []string
+			orig := &routeRecordingNotifier{sent: &sent}
+			cfg := &Config{APIVersion: "cloud-build-notifiers/v1", Spec: tc.spec}
+
+			tmpls := make([]string, len(tc.spec.Routes()))
+			n, err := setUpNotifier(ctx, orig, cfg, tmpls, new(setupCheckSecretGetter), nil, nil)
+			if err != nil {
+				t.Fatalf("setUpNotifier failed: %v", err)
+			}
+
+			if len(tc.spec.Routes()) == 1 && n != Notifier(orig) {
+				t.Errorf("setUpNotifier returned %v, want the original notifier for a single route", n)
+			}
+
+			if err := n.SendNotification(ctx, &cbpb.Build{Id: "some-build"}); err != nil {
+				t.Fatalf("SendNotification failed: %v", err)
+			}
+
+			if diff := cmp.Diff(tc.wantSent, sent); diff != "" {
+				t.Errorf("unexpected sent notifications diff: (want- got+)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMultiNotifierErrors(t *testing.T) {
+	sendErr := errors.New("failed to reticulate splines")
+	mn := &multiNotifier{routes: []Notifier{&errNotifier{}, &errNotifier{sendErr}}}
+
+	err := mn.SendNotification(context.Background(), new(cbpb.Build))
+	if !errors.Is(err, sendErr) {
+		t.Errorf("SendNotification returned %v, want an error wrapping %v", err, sendErr)
+	}
+}
+
+func TestNewTemplateView(t *testing.T) {
+	ctx := context.Background()
+	em := &EventMetadata{ID: "some-message-id"}
+	params := map[string]string{"greeting": "hi"}
+
+	// The Build fixes the trigger that a previous Build broke.
+	ss := newMemoryStatusStore()
+	recordStatus(ctx, ss, &cbpb.Build{Id: "broken-build-id", BuildTriggerId: "some-trigger-id", Status: cbpb.Build_FAILURE})
+	build := &cbpb.Build{Id: "some-build-id", BuildTriggerId: "some-trigger-id", Status: cbpb.Build_SUCCESS}
+	tr := transition(ctx, ss, build)
+
+	got := NewTemplateView(withTransition(withEventMetadata(ctx, em), tr), build, params)
+	want := &TemplateView{
+		Build:      &BuildView{Build: build},
+		Params:     params,
+		Event:      em,
+		Transition: &Transition{Kind: TransitionFixed, PreviousStatus: cbpb.Build_FAILURE, PreviousBuildID: "broken-build-id"},
+	}
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Errorf("unexpected TemplateView diff: (want- got+)\n%s", diff)
+	}
+}
+
 type fakeNotifier struct {
 	notifs chan *cbpb.Build
 }
@@ -805,6 +922,136 @@ func TestNewReceiverError(t *testing.T) {
 	}
 }
 
+type errDeadLetterSink struct {
+	err error
+}
+
+func (e *errDeadLetterSink) Write(_ context.Context, _ *deadLetter) error {
+	return e.err
+}
+
+func TestReceiverRetryAndDeadLetter(t *testing.T) {
+	dlPath := filepath.Join(t.TempDir(), "dead-letters.jsonl")
+	for _, tc := range []struct {
+		name     string
+		sink     deadLetterSink
+		wantCode int
+	}{
+		{
+			name:     "no dead-letter sink",
+			wantCode: http.StatusInternalServerError,
+		}, {
+			name:     "dead-letter sink write",
+			sink:     &fileDeadLetterSink{path: dlPath},
+			wantCode: http.StatusOK,
+		}, {
+			name:     "dead-letter sink failure",
+			sink:     &errDeadLetterSink{errors.New("bucket is on fire")},
+			wantCode: http.StatusInternalServerError,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			n := &errNotifier{errors.New("failed to reticulate splines")}
+			params := &receiverParams{
+				retry:      &RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond},
+				deadLetter: tc.sink,
+			}
+			handler := newReceiver(n, params)
+
+			req := httptest.NewRequest(http.MethodPost, "http://notifer.example.com/", buildToBuffer(t, &cbpb.Build{Id: "some-build-id"}))
+			w := httptest.NewRecorder()
+
+			handler(w, req)
+
+			if s := w.Result().StatusCode; s != tc.wantCode {
+				t.Errorf("result.StatusCode = %d, expected %d", s, tc.wantCode)
+			}
+		})
+	}
+
+	b, err := os.ReadFile(dlPath)
+	if err != nil {
+		t.Fatalf("failed to read dead-letter file: %v", err)
+	}
+	dl := new(deadLetter)
+	if err := json.Unmarshal(b, dl); err != nil {
+		t.Fatalf("failed to decode dead letter %q: %v", b, err)
+	}
+	if dl.MessageID != "id-does-not-matter" || dl.Attempts != 2 || dl.Error == "" {
+		t.Errorf("unexpected dead letter: %+v", dl)
+	}
+}
+
+// countingDeadLetterSink counts the dead letters that it is asked to write.
+type countingDeadLetterSink struct {
+	writes int
+}
+
+func (c *countingDeadLetterSink) Write(_ context.Context, _ *deadLetter) error {
+	c.writes++
+	return nil
+}
+
+func TestReceiverNacksRateLimited(t *testing.T) {
+	n := &countingNotifier{err: fmt.Errorf("destination %q would be over its rate limit: %w", "route/0", ErrRateLimited)}
+	dls := new(countingDeadLetterSink)
+	params := &receiverParams{
+		retry:      &RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond},
+		deadLetter: dls,
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "http://notifer.example.com/", buildToBuffer(t, &cbpb.Build{Id: "some-build-id"}))
+	w := httptest.NewRecorder()
+	newReceiver(n, params)(w, req)
+
+	if s := w.Result().StatusCode; s != http.StatusInternalServerError {
+		t.Errorf("result.StatusCode = %d, expected %d", s, http.StatusInternalServerError)
+	}
+	if n.sends != 1 {
+		t.Errorf("got %d sends, want 1 since rate limited notifications are not retried", n.sends)
+	}
+	if dls.writes != 0 {
+		t.Errorf("got %d dead letters, want 0 since rate limited messages are nacked", dls.writes)
+	}
+}
+
+// countingNotifier counts its sends and fails all of them with err (if non-nil).
+type countingNotifier struct {
+	sends int
+	err   error
+}
+
+func (n *countingNotifier) SetUp(_ context.Context, _ *Config, _ string, _ SecretGetter, _ BindingResolver) error {
+	return nil
+}
+
+func (n *countingNotifier) SendNotification(_ context.Context, _ *cbpb.Build) error {
+	n.sends++
+	return n.err
+}
+
+func TestReceiverRetriesFailedRoutesOnly(t *testing.T) {
+	healthy, failing := new(countingNotifier), &countingNotifier{err: errors.New("failed to reticulate splines")}
+	handler := newReceiver(&multiNotifier{routes: []Notifier{healthy, failing}}, &receiverParams{
+		retry:  &RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond},
+		dedupe: newLRUDedupeStore(10),
+	})
+
+	// The second request is Pub/Sub's redelivery of the nacked message.
+	for i, wantFailing := range []int{3, 6} {
+		req := httptest.NewRequest(http.MethodPost, "http://notifer.example.com/", buildToBuffer(t, &cbpb.Build{Id: "some-build-id"}))
+		w := httptest.NewRecorder()
+		handler(w, req)
+
+		if s := w.Result().StatusCode; s != http.StatusInternalServerError {
+			t.Errorf("delivery %d: result.StatusCode = %d, expected %d", i, s, http.StatusInternalServerError)
+		}
+		if healthy.sends != 1 || failing.sends != wantFailing {
+			t.Errorf("delivery %d: got %d sends to the healthy route and %d to the failing one, want 1 and %d", i, healthy.sends, failing.sends, wantFailing)
+		}
+	}
+}
+
 type fatalNotifier struct {
 	t *testing.T
 }
@@ -893,7 +1140,7 @@ func TestParseTemplate(t *testing.T) {
 		},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
-			got, err := parseTemplate(ctx, tc.tmpl, validFakeFactory)
+			got, err := parseTemplate(ctx, tc.tmpl, &configSource{grf: validFakeFactory}, "")
 			if err != nil {
 				if !tc.wantErr {
 					t.Fatalf("parseTemplate(%v) got unexpected error: %v", tc.tmpl, err)
@@ -0,0 +1,87 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifiers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"google.golang.org/api/idtoken"
+)
+
+// PushAuthConfig is the data container for configuring the verification of the OIDC tokens that Pub/Sub push
+// subscriptions attach to their requests.
+type PushAuthConfig struct {
+	// Audience is the expected `aud` claim, i.e. the audience that is set on the push subscription.
+	Audience string `yaml:"audience"`
+	// ServiceAccountEmail is the expected `email` claim, i.e. the push subscription's service account.
+	// If empty, tokens for any service account are accepted.
+	ServiceAccountEmail string `yaml:"serviceAccountEmail"`
+}
+
+func validatePushAuthConfig(cfg *PushAuthConfig) error {
+	if cfg.Audience == "" {
+		return errors.New("expected pushAuth audience to be present")
+	}
+	return nil
+}
+
+// oidcVerifier verifies the Google-signed OIDC bearer tokens of incoming requests. The signature, expiry, and audience
+// of a token are checked by an idtoken.Validator, which caches Google's signing keys.
+type oidcVerifier struct {
+	cfg       *PushAuthConfig
+	validator *idtoken.Validator
+}
+
+// newOIDCVerifier returns an oidcVerifier for the given config. The options configure the client that Google's signing
+// keys are fetched with.
+func newOIDCVerifier(ctx context.Context, cfg *PushAuthConfig, opts ...idtoken.ClientOption) (*oidcVerifier, error) {
+	v, err := idtoken.NewValidator(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create an OIDC token validator: %w", err)
+	}
+	return &oidcVerifier{cfg: cfg, validator: v}, nil
+}
+
+// verifyRequest verifies the bearer token in the given request's Authorization header.
+func (o *oidcVerifier) verifyRequest(r *http.Request) error {
+	auth := r.Header.Get("Authorization")
+	token, ok := strings.CutPrefix(auth, "Bearer ")
+	if !ok || token == "" {
+		return errors.New("expected an `Authorization: Bearer` header")
+	}
+	return o.verify(r.Context(), token)
+}
+
+// verify validates the given token and checks that it was issued to the expected service account (if any).
+func (o *oidcVerifier) verify(ctx context.Context, token string) error {
+	payload, err := o.validator.Validate(ctx, token, o.cfg.Audience)
+	if err != nil {
+		return fmt.Errorf("failed to validate token: %w", err)
+	}
+	if o.cfg.ServiceAccountEmail == "" {
+		return nil
+	}
+
+	email, _ := payload.Claims["email"].(string)
+	verified, _ := payload.Claims["email_verified"].(bool)
+	if email != o.cfg.ServiceAccountEmail || !verified {
+		return fmt.Errorf("got unexpected (or unverified) token email %q", email)
+	}
+	return nil
+}
@@ -0,0 +1,295 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifiers
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/api/option"
+)
+
+const (
+	testAudience = "https://notifier.example.com/"
+	testEmail    = "pusher@p.iam.gserviceaccount.com"
+)
+
+// fakeJWKS is a local stand-in for Google's JWKS endpoint that signs tokens with its own keys.
+type fakeJWKS struct {
+	*httptest.Server
+	keys    map[string]*rsa.PrivateKey
+	fetches int32
+}
+
+func newFakeJWKS(t *testing.T, kids ...string) *fakeJWKS {
+	t.Helper()
+	f := &fakeJWKS{keys: map[string]*rsa.PrivateKey{}}
+	for _, kid := range kids {
+		k, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatalf("failed to generate key: %v", err)
+		}
+		f.keys[kid] = k
+	}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		atomic.AddInt32(&f.fetches, 1)
+		type jwk struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		}
+		var jwks struct {
+			Keys []jwk `json:"keys"`
+		}
+		for kid, k := range f.keys {
+			jwks.Keys = append(jwks.Keys, jwk{
+				Kty: "RSA",
+				Kid: kid,
+				N:   base64.RawURLEncoding.EncodeToString(k.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+			})
+		}
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		json.NewEncoder(w).Encode(jwks)
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+// verifier returns an oidcVerifier for the given config whose requests for Google's signing keys go to the fake JWKS.
+func (f *fakeJWKS) verifier(t *testing.T, cfg *PushAuthConfig) *oidcVerifier {
+	t.Helper()
+	u, err := url.Parse(f.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		r = r.Clone(r.Context())
+		r.URL.Scheme, r.URL.Host = u.Scheme, u.Host
+		return http.DefaultTransport.RoundTrip(r)
+	})}
+	v, err := newOIDCVerifier(context.Background(), cfg, option.WithHTTPClient(client))
+	if err != nil {
+		t.Fatalf("newOIDCVerifier failed: %v", err)
+	}
+	return v
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// sign returns a token with the given claims, signed with the key of the given ID (which may be unknown to the JWKS).
+func (f *fakeJWKS) sign(t *testing.T, kid string, claims map[string]interface{}) string {
+	t.Helper()
+	key, ok := f.keys[kid]
+	if !ok {
+		var err error
+		if key, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+			t.Fatalf("failed to generate key: %v", err)
+		}
+	}
+	enc := func(v interface{}) string {
+		b, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("failed to marshal %v: %v", v, err)
+		}
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+	signed := enc(map[string]string{"alg": "RS256", "kid": kid, "typ": "JWT"}) + "." + enc(claims)
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+// testClaims returns the claims of a valid token with the given overrides.
+func testClaims(overrides map[string]interface{}) map[string]interface{} {
+	now := time.Now()
+	c := map[string]interface{}{
+		"iss":            "https://accounts.google.com",
+		"aud":            testAudience,
+		"email":          testEmail,
+		"email_verified": true,
+		"iat":            now.Add(-time.Minute).Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+	}
+	for k, v := range overrides {
+		c[k] = v
+	}
+	return c
+}
+
+func TestOIDCVerifier(t *testing.T) {
+	jwks := newFakeJWKS(t, "key-1")
+	for _, tc := range []struct {
+		name    string
+		cfg     PushAuthConfig
+		header  string
+		wantErr bool
+	}{{
+		name:   "valid",
+		cfg:    PushAuthConfig{Audience: testAudience, ServiceAccountEmail: testEmail},
+		header: "Bearer " + jwks.sign(t, "key-1", testClaims(nil)),
+	}, {
+		name:   "any email",
+		cfg:    PushAuthConfig{Audience: testAudience},
+		header: "Bearer " + jwks.sign(t, "key-1", testClaims(map[string]interface{}{"email": "x@example.com"})),
+	}, {
+		name:    "missing header",
+		cfg:     PushAuthConfig{Audience: testAudience},
+		wantErr: true,
+	}, {
+		name:    "not a bearer token",
+		cfg:     PushAuthConfig{Audience: testAudience},
+		header:  "Basic dXNlcjpwYXNz",
+		wantErr: true,
+	}, {
+		name:    "malformed token",
+		cfg:     PushAuthConfig{Audience: testAudience},
+		header:  "Bearer not.a-token",
+		wantErr: true,
+	}, {
+		name:    "unknown key",
+		cfg:     PushAuthConfig{Audience: testAudience},
+		header:  "Bearer " + jwks.sign(t, "forged-key", testClaims(nil)),
+		wantErr: true,
+	}, {
+		name: "tampered claims",
+		cfg:  PushAuthConfig{Audience: testAudience, ServiceAccountEmail: testEmail},
+		header: func() string {
+			parts := strings.Split(jwks.sign(t, "key-1", testClaims(map[string]interface{}{"email": "x@example.com"})), ".")
+			b, _ := json.Marshal(testClaims(nil))
+			return "Bearer " + parts[0] + "." + base64.RawURLEncoding.EncodeToString(b) + "." + parts[2]
+		}(),
+		wantErr: true,
+	}, {
+		name:    "wrong audience",
+		cfg:     PushAuthConfig{Audience: testAudience},
+		header:  "Bearer " + jwks.sign(t, "key-1", testClaims(map[string]interface{}{"aud": "https://other.example.com/"})),
+		wantErr: true,
+	}, {
+		name:    "wrong email",
+		cfg:     PushAuthConfig{Audience: testAudience, ServiceAccountEmail: testEmail},
+		header:  "Bearer " + jwks.sign(t, "key-1", testClaims(map[string]interface{}{"email": "x@example.com"})),
+		wantErr: true,
+	}, {
+		name:    "unverified email",
+		cfg:     PushAuthConfig{Audience: testAudience, ServiceAccountEmail: testEmail},
+		header:  "Bearer " + jwks.sign(t, "key-1", testClaims(map[string]interface{}{"email_verified": false})),
+		wantErr: true,
+	}, {
+		name:    "expired",
+		cfg:     PushAuthConfig{Audience: testAudience},
+		header:  "Bearer " + jwks.sign(t, "key-1", testClaims(map[string]interface{}{"exp": time.Now().Add(-time.Hour).Unix()})),
+		wantErr: true,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := tc.cfg
+			v := jwks.verifier(t, &cfg)
+
+			req := httptest.NewRequest(http.MethodPost, testAudience, nil)
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+			err := v.verifyRequest(req)
+			if err != nil {
+				if tc.wantErr {
+					t.Logf("got expected error: %v", err)
+					return
+				}
+				t.Fatalf("verifyRequest got unexpected error: %v", err)
+			}
+			if tc.wantErr {
+				t.Fatal("verifyRequest unexpectedly succeeded")
+			}
+		})
+	}
+}
+
+func TestOIDCVerifierCachesKeys(t *testing.T) {
+	jwks := newFakeJWKS(t, "key-1")
+	v := jwks.verifier(t, &PushAuthConfig{Audience: testAudience})
+	for i := 0; i < 2; i++ {
+		if err := v.verify(context.Background(), jwks.sign(t, "key-1", testClaims(nil))); err != nil {
+			t.Fatalf("verify got unexpected error: %v", err)
+		}
+	}
+	if got := atomic.LoadInt32(&jwks.fetches); got != 1 {
+		t.Errorf("got %d JWKS fetches, want 1 (cached)", got)
+	}
+}
+
+func TestReceiverPushAuth(t *testing.T) {
+	jwks := newFakeJWKS(t, "key-1")
+	body := fmt.Sprintf(`{"message": {"data": %q, "id": "message-id"}, "subscription": "projects/p/subscriptions/s"}`,
+		base64.StdEncoding.EncodeToString([]byte(ceBuildJSON)))
+
+	for _, tc := range []struct {
+		name       string
+		token      string
+		wantStatus int
+	}{{
+		name:       "authenticated",
+		token:      jwks.sign(t, "key-1", testClaims(nil)),
+		wantStatus: http.StatusOK,
+	}, {
+		name:       "wrong service account",
+		token:      jwks.sign(t, "key-1", testClaims(map[string]interface{}{"email": "x@example.com"})),
+		wantStatus: http.StatusUnauthorized,
+	}, {
+		name:       "unauthenticated",
+		wantStatus: http.StatusUnauthorized,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			n := new(eventRecordingNotifier)
+			params := &receiverParams{
+				pushAuth: jwks.verifier(t, &PushAuthConfig{Audience: testAudience, ServiceAccountEmail: testEmail}),
+			}
+			req := httptest.NewRequest(http.MethodPost, testAudience, strings.NewReader(body))
+			if tc.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+			w := httptest.NewRecorder()
+
+			newReceiver(n, params)(w, req)
+			if s := w.Result().StatusCode; s != tc.wantStatus {
+				t.Fatalf("result.StatusCode = %d, expected %d", s, tc.wantStatus)
+			}
+			if sent := n.buildID != ""; sent != (tc.wantStatus == http.StatusOK) {
+				t.Errorf("notification sent = %t, want %t", sent, tc.wantStatus == http.StatusOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifiers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// pullSettings configures the streaming-pull subscriber that is used in `MODE=pull`.
+type pullSettings struct {
+	project      string
+	subscription string // The subscription ID within the project.
+	// The following are passed to pubsub.ReceiveSettings; zero values keep the client library's defaults.
+	numGoroutines          int
+	maxOutstandingMessages int
+	maxOutstandingBytes    int
+}
+
+// pullSettingsFromEnv reads the pullSettings from the environment. PUBSUB_SUBSCRIPTION is either a full subscription
+// name (`projects/<project>/subscriptions/<id>`) or an ID in the PROJECT_ID project.
+func pullSettingsFromEnv() (*pullSettings, error) {
+	sub, ok := GetEnv("PUBSUB_SUBSCRIPTION")
+	if !ok {
+		return nil, errors.New("expected PUBSUB_SUBSCRIPTION to be non-empty in pull mode")
+	}
+	project, _ := GetEnv("PROJECT_ID")
+	ps, err := parseSubscription(sub, project)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range []struct {
+		name string
+		dst  *int
+	}{
+		{"PULL_NUM_GOROUTINES", &ps.numGoroutines},
+		{"PULL_MAX_OUTSTANDING_MESSAGES", &ps.maxOutstandingMessages},
+		{"PULL_MAX_OUTSTANDING_BYTES", &ps.maxOutstandingBytes},
+	} {
+		v, ok := GetEnv(f.name)
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("expected %s %q to be a positive integer", f.name, v)
+		}
+		*f.dst = n
+	}
+	return ps, nil
+}
+
+func parseSubscription(sub, project string) (*pullSettings, error) {
+	if strings.HasPrefix(sub, "projects/") {
+		split := strings.Split(sub, "/")
+		if len(split) != 4 || split[1] == "" || split[2] != "subscriptions" || split[3] == "" {
+			return nil, fmt.Errorf("subscription has incorrect format (expected form: `projects/<project>/subscriptions/<id>`): %q", sub)
+		}
+		return &pullSettings{project: split[1], subscription: split[3]}, nil
+	}
+	if strings.Contains(sub, "/") {
+		return nil, fmt.Errorf("expected subscription %q to be a subscription ID or a full subscription name", sub)
+	}
+	if project == "" {
+		return nil, fmt.Errorf("expected PROJECT_ID to be non-empty for subscription ID %q", sub)
+	}
+	return &pullSettings{project: project, subscription: sub}, nil
+}
+
+// runPull receives messages from the subscription until the context is done, acking every message that was handled
+// and nacking the rest so that Pub/Sub redelivers them.
+// The client connects to the Pub/Sub emulator instead if PUBSUB_EMULATOR_HOST is set.
+func runPull(ctx context.Context, client *pubsub.Client, notifier Notifier, params *receiverParams, ps *pullSettings) error {
+	sub := client.Subscription(ps.subscription)
+	sub.ReceiveSettings.NumGoroutines = ps.numGoroutines
+	sub.ReceiveSettings.MaxOutstandingMessages = ps.maxOutstandingMessages
+	sub.ReceiveSettings.MaxOutstandingBytes = ps.maxOutstandingBytes
+
+	Infof(ctx, "pulling PubSub messages from subscription %q", sub)
+	return sub.Receive(ctx, func(ctx context.Context, m *pubsub.Message) {
+		// The callback's context is canceled when we stop receiving, but notifications that are already being sent
+		// should be drained rather than interrupted.
+		if handlePulled(context.WithoutCancel(ctx), notifier, params, sub.String(), m) {
+			m.Ack()
+		} else {
+			m.Nack()
+		}
+	})
+}
+
+// handlePulled handles the given pulled message the same way as a pushed one and returns true iff it should be acked.
+func handlePulled(ctx context.Context, notifier Notifier, params *receiverParams, subscription string, m *pubsub.Message) bool {
+	Debugf(ctx, "got PubSub message with ID %q from subscription %q", m.ID, subscription)
+
+	pspw := &pubSubPushWrapper{
+		Message: pubSubPushMessage{
+			Data:        m.Data,
+			Attributes:  m.Attributes,
+			ID:          m.ID,
+			PublishTime: m.PublishTime.Format(time.RFC3339Nano),
+		},
+		Subscription: subscription,
+	}
+	// Dead letters keep the same (push) envelope regardless of the mode.
+	envelope, err := json.Marshal(pspw)
+	if err != nil {
+		Errorf(ctx, "failed to encode PubSub message %q: %v", m.ID, err)
+		return false
+	}
+
+	// Continue the trace of the publisher, if it attached its trace context to the message attributes.
+	ctx, span := otel.Tracer(tracerName).Start(tracePropagator.Extract(ctx, propagation.MapCarrier(m.Attributes)),
+		"ReceiveMessage", trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(attribute.String("messaging.message.id", m.ID), attribute.String("messaging.subscription", subscription)))
+	defer span.End()
+
+	ctx = withEventMetadata(ctx, pubSubEventMetadata(subscription, &pspw.Message))
+	if res := handleMessage(ctx, notifier, params, &pspw.Message, envelope); res != messageDone {
+		span.SetStatus(codes.Error, "message was not handled")
+		return false
+	}
+	return true
+}
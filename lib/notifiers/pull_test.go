@@ -0,0 +1,217 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifiers
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	cbpb "cloud.google.com/go/cloudbuild/apiv1/v2/cloudbuildpb"
+	"cloud.google.com/go/pubsub"
+	"cloud.google.com/go/pubsub/pstest"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func TestParseSubscription(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		sub     string
+		project string
+		want    *pullSettings
+		wantErr bool
+	}{{
+		name: "full name",
+		sub:  "projects/my-project/subscriptions/my-sub",
+		want: &pullSettings{project: "my-project", subscription: "my-sub"},
+	}, {
+		name:    "ID with project",
+		sub:     "my-sub",
+		project: "my-project",
+		want:    &pullSettings{project: "my-project", subscription: "my-sub"},
+	}, {
+		name:    "ID without project",
+		sub:     "my-sub",
+		wantErr: true,
+	}, {
+		name:    "topic name",
+		sub:     "projects/my-project/topics/cloud-builds",
+		wantErr: true,
+	}, {
+		name:    "partial name",
+		sub:     "my-project/my-sub",
+		project: "my-project",
+		wantErr: true,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseSubscription(tc.sub, tc.project)
+			if err != nil {
+				if tc.wantErr {
+					t.Logf("got expected error: %v", err)
+					return
+				}
+				t.Fatalf("parseSubscription(%q, %q) got unexpected error: %v", tc.sub, tc.project, err)
+			}
+			if tc.wantErr {
+				t.Fatalf("parseSubscription(%q, %q) = %+v, want an error", tc.sub, tc.project, got)
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(pullSettings{})); diff != "" {
+				t.Errorf("parseSubscription(%q, %q) produced unexpected diff: (want- got+)\n%s", tc.sub, tc.project, diff)
+			}
+		})
+	}
+}
+
+func TestPullSettingsFromEnv(t *testing.T) {
+	t.Setenv("PUBSUB_SUBSCRIPTION", "projects/my-project/subscriptions/my-sub")
+	t.Setenv("PULL_NUM_GOROUTINES", "2")
+	t.Setenv("PULL_MAX_OUTSTANDING_MESSAGES", "10")
+
+	got, err := pullSettingsFromEnv()
+	if err != nil {
+		t.Fatalf("pullSettingsFromEnv failed: %v", err)
+	}
+	want := &pullSettings{project: "my-project", subscription: "my-sub", numGoroutines: 2, maxOutstandingMessages: 10}
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(pullSettings{})); diff != "" {
+		t.Errorf("pullSettingsFromEnv produced unexpected diff: (want- got+)\n%s", diff)
+	}
+
+	t.Setenv("PULL_MAX_OUTSTANDING_BYTES", "lots")
+	if _, err := pullSettingsFromEnv(); err == nil {
+		t.Error("pullSettingsFromEnv with a non-integer setting unexpectedly succeeded")
+	}
+}
+
+// pullRecordingNotifier records the IDs of the Builds that it was sent and fails to send the `fail` Build.
+type pullRecordingNotifier struct {
+	mtx sync.Mutex
+	ids []string
+}
+
+func (p *pullRecordingNotifier) SetUp(_ context.Context, _ *Config, _ string, _ SecretGetter, _ BindingResolver) error {
+	return nil
+}
+
+func (p *pullRecordingNotifier) SendNotification(_ context.Context, build *cbpb.Build) error {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	p.ids = append(p.ids, build.GetId())
+	if build.GetId() == "fail" {
+		return errors.New("failed to send")
+	}
+	return nil
+}
+
+func (p *pullRecordingNotifier) sent(id string) bool {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	for _, i := range p.ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRunPull(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// pstest is an in-memory stand-in for Pub/Sub (like the emulator).
+	srv := pstest.NewServer()
+	defer srv.Close()
+	conn, err := grpc.Dial(srv.Addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client, err := pubsub.NewClient(ctx, "test-project", option.WithGRPCConn(conn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	topic, err := client.CreateTopic(ctx, cloudBuildTopic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.CreateSubscription(ctx, "notifier", pubsub.SubscriptionConfig{Topic: topic}); err != nil {
+		t.Fatal(err)
+	}
+
+	okID := srv.Publish("projects/test-project/topics/"+cloudBuildTopic, []byte(`{"id": "ok", "status": "SUCCESS"}`), nil)
+	failID := srv.Publish("projects/test-project/topics/"+cloudBuildTopic, []byte(`{"id": "fail", "status": "FAILURE"}`), nil)
+
+	n := new(pullRecordingNotifier)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- runPull(ctx, client, n, new(receiverParams), &pullSettings{project: "test-project", subscription: "notifier", numGoroutines: 1})
+	}()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		ok, fail := srv.Message(okID), srv.Message(failID)
+		if ok.Acks > 0 && n.sent("fail") {
+			if fail.Acks != 0 {
+				t.Errorf("failed message was acked %d times, want it to be nacked", fail.Acks)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for messages to be handled: got %d acks for the sent message", ok.Acks)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	if err := <-errc; err != nil {
+		t.Errorf("runPull returned unexpected error: %v", err)
+	}
+}
+
+// recordingDeadLetterSink records every dead letter that is written to it.
+type recordingDeadLetterSink struct {
+	letters []*deadLetter
+}
+
+func (r *recordingDeadLetterSink) Write(_ context.Context, dl *deadLetter) error {
+	r.letters = append(r.letters, dl)
+	return nil
+}
+
+func TestHandlePulledEnvelope(t *testing.T) {
+	dls := new(recordingDeadLetterSink)
+	params := &receiverParams{deadLetter: dls}
+
+	m := &pubsub.Message{ID: "some-id", Data: []byte(`{"id": "fail"}`), PublishTime: time.Unix(0, 0).UTC()}
+	if !handlePulled(context.Background(), new(pullRecordingNotifier), params, "projects/p/subscriptions/s", m) {
+		t.Fatal("handlePulled = false for a dead-lettered message, want true")
+	}
+
+	if len(dls.letters) != 1 {
+		t.Fatalf("got %d dead letters, want 1", len(dls.letters))
+	}
+	want := `{"message":{"data":"eyJpZCI6ICJmYWlsIn0=","id":"some-id","publishTime":"1970-01-01T00:00:00Z"},"subscription":"projects/p/subscriptions/s"}`
+	if diff := cmp.Diff(want, string(dls.letters[0].Envelope)); diff != "" {
+		t.Errorf("unexpected envelope diff: (want- got+)\n%s", diff)
+	}
+}
@@ -0,0 +1,348 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifiers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	cbpb "cloud.google.com/go/cloudbuild/apiv1/v2/cloudbuildpb"
+	"golang.org/x/time/rate"
+)
+
+const (
+	rateLimitDelay   = "delay"
+	rateLimitSummary = "summary"
+	rateLimitDrop    = "drop"
+
+	defaultRateLimitPer      = time.Minute
+	defaultRateLimitMaxDelay = 10 * time.Second
+)
+
+// ErrRateLimited is returned (wrapped) by SendNotification when a notification would have to be delayed for longer
+// than the `delay` rate limit policy allows. The message is then nacked so that Pub/Sub redelivers it later.
+var ErrRateLimited = errors.New("rate limited")
+
+// RateLimitConfig is the data container for configuring a token bucket per notification destination.
+type RateLimitConfig struct {
+	// Rate is the number of notifications that are sent to a destination per Per.
+	Rate int `yaml:"rate"`
+	// Per is the period of Rate. Defaults to a minute.
+	Per time.Duration `yaml:"per"`
+	// Burst is the number of notifications that can be sent to a destination at once. Defaults to Rate.
+	Burst int `yaml:"burst"`
+	// Policy is what happens to the notifications that exceed the limit: they are either delayed (`delay`, the
+	// default), merged into a summary that is sent once the limit allows it (`summary`), or dropped (`drop`).
+	Policy string `yaml:"policy"`
+	// MaxDelay is the longest that the `delay` policy holds a notification back. Defaults to 10s.
+	MaxDelay time.Duration `yaml:"maxDelay"`
+}
+
+func validateRateLimitConfig(r *RateLimitConfig) error {
+	if r.Rate < 1 {
+		return fmt.Errorf("expected rateLimit.rate to be positive, got %d", r.Rate)
+	}
+	if r.Per < 0 || r.Burst < 0 || r.MaxDelay < 0 {
+		return fmt.Errorf("expected rateLimit.per, burst, and maxDelay to be non-negative, got per=%v, burst=%d, and maxDelay=%v",
+			r.Per, r.Burst, r.MaxDelay)
+	}
+	switch r.Policy {
+	case "", rateLimitDelay, rateLimitSummary, rateLimitDrop:
+	default:
+		return fmt.Errorf("expected rateLimit.policy %q to be one of `delay`, `summary`, or `drop`", r.Policy)
+	}
+	return nil
+}
+
+func (r *RateLimitConfig) policy() string {
+	if r.Policy == "" {
+		return rateLimitDelay
+	}
+	return r.Policy
+}
+
+func (r *RateLimitConfig) limit() rate.Limit {
+	per := r.Per
+	if per == 0 {
+		per = defaultRateLimitPer
+	}
+	return rate.Every(per / time.Duration(r.Rate))
+}
+
+func (r *RateLimitConfig) burst() int {
+	if r.Burst == 0 {
+		return r.Rate
+	}
+	return r.Burst
+}
+
+func (r *RateLimitConfig) maxDelay() time.Duration {
+	if r.MaxDelay == 0 {
+		return defaultRateLimitMaxDelay
+	}
+	return r.MaxDelay
+}
+
+// DestinationKeyer is an optional interface for Notifiers whose destination depends on the Build (e.g. the GitHub
+// repository that an issue is filed in). Every destination key gets its own rate limit. Notifiers that do not
+// implement it (or return an empty key) share a single rate limit per notification route.
+type DestinationKeyer interface {
+	DestinationKey(*cbpb.Build) string
+}
+
+// RateLimitSummary describes the notifications that the `summary` rate limit policy held back.
+type RateLimitSummary struct {
+	// Count is the number of notifications that were held back since the last one that was sent to the destination.
+	// When a summary is sent on its own, its Build is the most recent of them.
+	Count int
+	// BuildIDs are the IDs of the Builds of the held back notifications.
+	BuildIDs []string
+	// Since is when the first of them was held back.
+	Since time.Time
+}
+
+type rateLimitSummaryKey struct{}
+
+// RateLimitSummaryFromContext returns the summary of the notifications that were held back before the one that is
+// being sent, or nil if there were none.
+func RateLimitSummaryFromContext(ctx context.Context) *RateLimitSummary {
+	s, _ := ctx.Value(rateLimitSummaryKey{}).(*RateLimitSummary)
+	return s
+}
+
+// rateLimiter holds a token bucket per destination key. It outlives Config reloads, so that reloading (or rotating a
+// secret) never resets the buckets.
+type rateLimiter struct {
+	now func() time.Time
+
+	mtx     sync.Mutex
+	buckets map[string]*bucket
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{now: time.Now, buckets: map[string]*bucket{}}
+}
+
+// drain sends the pending summary of every destination, so that shutting down does not lose the notifications that
+// were held back for them.
+func (r *rateLimiter) drain(ctx context.Context) {
+	r.mtx.Lock()
+	buckets := make([]*bucket, 0, len(r.buckets))
+	for _, b := range r.buckets {
+		buckets = append(buckets, b)
+	}
+	r.mtx.Unlock()
+
+	for _, b := range buckets {
+		b.drain(ctx)
+	}
+}
+
+// bucket returns the token bucket of the given destination key, updating its limits to the given config.
+func (r *rateLimiter) bucket(key string, cfg *RateLimitConfig) *bucket {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	b, ok := r.buckets[key]
+	if !ok {
+		b = &bucket{key: key, now: r.now, lim: rate.NewLimiter(cfg.limit(), cfg.burst())}
+		r.buckets[key] = b
+		return b
+	}
+	if b.lim.Limit() != cfg.limit() {
+		b.lim.SetLimitAt(r.now(), cfg.limit())
+	}
+	if b.lim.Burst() != cfg.burst() {
+		b.lim.SetBurstAt(r.now(), cfg.burst())
+	}
+	return b
+}
+
+type bucket struct {
+	key string
+	now func() time.Time
+	lim *rate.Limiter
+
+	mtx sync.Mutex
+	// The notifications that were held back by the `summary` policy, and what it takes to send the summary of them.
+	pending       *RateLimitSummary
+	pendingCtx    context.Context
+	pendingBuild  *cbpb.Build
+	pendingSender Notifier
+	// timer sends the pending summary once the bucket gains a token.
+	timer *time.Timer
+}
+
+// hold adds the given notification to the pending summary and, for the first one, schedules sending the summary.
+func (b *bucket) hold(ctx context.Context, n Notifier, build *cbpb.Build) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	if b.pending == nil {
+		b.pending = &RateLimitSummary{Since: b.now()}
+		b.timer = time.AfterFunc(b.interval(), b.flush)
+	}
+	b.pending.Count++
+	b.pending.BuildIDs = append(b.pending.BuildIDs, build.GetId())
+	// Sending the summary must neither be canceled with the message nor lose its log fields.
+	b.pendingCtx, b.pendingBuild, b.pendingSender = context.WithoutCancel(ctx), build, n
+}
+
+// take returns and clears the pending summary, if any.
+func (b *bucket) take() *RateLimitSummary {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	s := b.pending
+	b.clear()
+	return s
+}
+
+// clear forgets the pending summary and stops the timer that would send it. b.mtx must be held.
+func (b *bucket) clear() {
+	if b.timer != nil {
+		b.timer.Stop()
+	}
+	b.pending, b.pendingCtx, b.pendingBuild, b.pendingSender, b.timer = nil, nil, nil, nil, nil
+}
+
+// flush sends the pending summary, if a notification that was let through has not already carried it.
+func (b *bucket) flush() {
+	b.mtx.Lock()
+	if b.pending == nil {
+		b.mtx.Unlock()
+		return
+	}
+	if !b.lim.AllowN(b.now(), 1) {
+		b.timer = time.AfterFunc(b.interval(), b.flush)
+		b.mtx.Unlock()
+		return
+	}
+	s, ctx, build, n := b.pending, b.pendingCtx, b.pendingBuild, b.pendingSender
+	b.clear()
+	b.mtx.Unlock()
+
+	b.send(ctx, s, build, n)
+}
+
+// drain sends the pending summary right away, regardless of the rate limit, since the notifier is shutting down.
+func (b *bucket) drain(ctx context.Context) {
+	b.mtx.Lock()
+	if b.pending == nil {
+		b.mtx.Unlock()
+		return
+	}
+	s, sctx, build, n := b.pending, b.pendingCtx, b.pendingBuild, b.pendingSender
+	b.clear()
+	b.mtx.Unlock()
+
+	sctx, cancel := detachedUntil(sctx, ctx)
+	defer cancel()
+	b.send(sctx, s, build, n)
+}
+
+func (b *bucket) send(ctx context.Context, s *RateLimitSummary, build *cbpb.Build, n Notifier) {
+	Infof(ctx, "sending a summary of %d rate limited notification(s) to destination %q", s.Count, b.key)
+	if err := n.SendNotification(context.WithValue(ctx, rateLimitSummaryKey{}, s), build); err != nil {
+		Errorf(ctx, "failed to send a summary of %d rate limited notification(s) to destination %q: %v", s.Count, b.key, err)
+	}
+}
+
+// interval is how long it takes for the bucket to gain a token.
+func (b *bucket) interval() time.Duration {
+	return time.Duration(float64(time.Second) / float64(b.lim.Limit()))
+}
+
+// rateLimitedNotifier is a Notifier that applies a rate limit to the notifications of a single route before they are
+// passed on to the route's notifier.
+type rateLimitedNotifier struct {
+	notifier Notifier
+	// filter is the route's filter. Only the Builds that match it count against the rate limit.
+	filter *CELPredicate
+	cfg    *RateLimitConfig
+	rl     *rateLimiter
+	// routeKey is the destination key of the notifications whose notifier is not a DestinationKeyer.
+	routeKey string
+}
+
+// SetUp is a no-op since the wrapped notifier is set up by setUpNotifier.
+func (r *rateLimitedNotifier) SetUp(_ context.Context, _ *Config, _ string, _ SecretGetter, _ BindingResolver) error {
+	return nil
+}
+
+func (r *rateLimitedNotifier) SendNotification(ctx context.Context, build *cbpb.Build) error {
+	if match, err := r.filter.eval(ctx, build); err != nil || !match {
+		// The notifier applies (and reports on) its own filter.
+		return r.notifier.SendNotification(ctx, build)
+	}
+
+	key := r.routeKey
+	if dk, ok := r.notifier.(DestinationKeyer); ok {
+		if k := dk.DestinationKey(build); k != "" {
+			key = k
+		}
+	}
+	b := r.rl.bucket(key, r.cfg)
+
+	switch r.cfg.policy() {
+	case rateLimitDrop:
+		if !b.lim.AllowN(r.rl.now(), 1) {
+			rateLimited.WithLabelValues(rateLimitDropped).Inc()
+			Warningf(ctx, "dropping notification for Build %q since destination %q is over its rate limit", build.GetId(), key)
+			return nil
+		}
+	case rateLimitSummary:
+		if !b.lim.AllowN(r.rl.now(), 1) {
+			rateLimited.WithLabelValues(rateLimitSummarized).Inc()
+			Infof(ctx, "holding back notification for Build %q for a summary since destination %q is over its rate limit", build.GetId(), key)
+			b.hold(ctx, r.notifier, build)
+			return nil
+		}
+		if s := b.take(); s != nil {
+			ctx = context.WithValue(ctx, rateLimitSummaryKey{}, s)
+		}
+	default:
+		res := b.lim.ReserveN(r.rl.now(), 1)
+		d := res.DelayFrom(r.rl.now())
+		if d > r.cfg.maxDelay() {
+			res.CancelAt(r.rl.now())
+			rateLimited.WithLabelValues(rateLimitRejected).Inc()
+			return fmt.Errorf("destination %q would be over its rate limit for %v: %w", key, d, ErrRateLimited)
+		}
+		if d > 0 {
+			rateLimited.WithLabelValues(rateLimitDelayed).Inc()
+			Infof(ctx, "delaying notification for Build %q by %v since destination %q is over its rate limit", build.GetId(), d, key)
+			t := time.NewTimer(d)
+			select {
+			case <-ctx.Done():
+				t.Stop()
+				res.CancelAt(r.rl.now())
+				return ctx.Err()
+			case <-t.C:
+			}
+		}
+	}
+	return r.notifier.SendNotification(ctx, build)
+}
+
+// CheckHealth checks the health of the wrapped notifier if it implements HealthChecker.
+func (r *rateLimitedNotifier) CheckHealth(ctx context.Context) error {
+	if hc, ok := r.notifier.(HealthChecker); ok {
+		return hc.CheckHealth(ctx)
+	}
+	return nil
+}
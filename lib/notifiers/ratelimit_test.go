@@ -0,0 +1,235 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifiers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	cbpb "cloud.google.com/go/cloudbuild/apiv1/v2/cloudbuildpb"
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// rateRecordingNotifier records the Builds that it is asked to send, with the size of their rate limit summary.
+// Its destination is the `_DEST` substitution of the Build.
+type rateRecordingNotifier struct {
+	mtx  sync.Mutex
+	sent []string
+	sump chan *RateLimitSummary
+}
+
+func (r *rateRecordingNotifier) SetUp(_ context.Context, _ *Config, _ string, _ SecretGetter, _ BindingResolver) error {
+	return nil
+}
+
+func (r *rateRecordingNotifier) SendNotification(ctx context.Context, build *cbpb.Build) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	sent := build.GetId()
+	if s := RateLimitSummaryFromContext(ctx); s != nil {
+		sent = fmt.Sprintf("%s+%v", sent, s.BuildIDs)
+		if r.sump != nil {
+			r.sump <- s
+		}
+	}
+	r.sent = append(r.sent, sent)
+	return nil
+}
+
+func (r *rateRecordingNotifier) DestinationKey(build *cbpb.Build) string {
+	return build.GetSubstitutions()["_DEST"]
+}
+
+func failedBuild(id, dest string) *cbpb.Build {
+	return &cbpb.Build{Id: id, Status: cbpb.Build_FAILURE, Substitutions: map[string]string{"_DEST": dest}}
+}
+
+func newRateLimitedNotifier(t *testing.T, n Notifier, cfg *RateLimitConfig, rl *rateLimiter) *rateLimitedNotifier {
+	t.Helper()
+	filter, err := MakeCELPredicate("build.status == Build.Status.FAILURE")
+	if err != nil {
+		t.Fatalf("MakeCELPredicate failed: %v", err)
+	}
+	return &rateLimitedNotifier{notifier: n, filter: filter, cfg: cfg, rl: rl, routeKey: "route/0"}
+}
+
+func TestRateLimitedNotifier(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	type send struct {
+		build   *cbpb.Build
+		advance time.Duration // How far the clock moves before the send.
+		wantErr error
+	}
+	for _, tc := range []struct {
+		name        string
+		cfg         *RateLimitConfig
+		sends       []send
+		wantSent    []string
+		wantOutcome string
+		wantCount   float64
+	}{{
+		name: "drop",
+		cfg:  &RateLimitConfig{Rate: 2, Policy: rateLimitDrop},
+		sends: []send{
+			{build: failedBuild("f1", "a")},
+			{build: failedBuild("f2", "a")},
+			// Builds that do not match the filter never count against the limit.
+			{build: &cbpb.Build{Id: "s1", Status: cbpb.Build_SUCCESS}},
+			{build: failedBuild("f3", "a")},
+			// Every destination has its own bucket.
+			{build: failedBuild("f4", "b")},
+			{build: failedBuild("f5", "a"), advance: 30 * time.Second},
+		},
+		wantSent:    []string{"f1", "f2", "s1", "f4", "f5"},
+		wantOutcome: rateLimitDropped,
+		wantCount:   1,
+	}, {
+		name: "delay beyond maxDelay",
+		cfg:  &RateLimitConfig{Rate: 1, Per: time.Hour, MaxDelay: time.Minute},
+		sends: []send{
+			{build: failedBuild("f1", "a")},
+			{build: failedBuild("f2", "a"), wantErr: ErrRateLimited},
+			{build: failedBuild("f3", "a"), advance: time.Hour},
+		},
+		wantSent:    []string{"f1", "f3"},
+		wantOutcome: rateLimitRejected,
+		wantCount:   1,
+	}, {
+		name: "summary",
+		cfg:  &RateLimitConfig{Rate: 1, Policy: rateLimitSummary},
+		sends: []send{
+			{build: failedBuild("f1", "a")},
+			{build: failedBuild("f2", "a")},
+			{build: failedBuild("f3", "a")},
+			{build: failedBuild("f4", "a"), advance: time.Minute},
+			{build: failedBuild("f5", "a"), advance: time.Minute},
+		},
+		wantSent:    []string{"f1", "f4+[f2 f3]", "f5"},
+		wantOutcome: rateLimitSummarized,
+		wantCount:   2,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			before := testutil.ToFloat64(rateLimited.WithLabelValues(tc.wantOutcome))
+			clock := now
+			rl := newRateLimiter()
+			rl.now = func() time.Time { return clock }
+			n := new(rateRecordingNotifier)
+			rn := newRateLimitedNotifier(t, n, tc.cfg, rl)
+
+			for i, s := range tc.sends {
+				clock = clock.Add(s.advance)
+				if err := rn.SendNotification(context.Background(), s.build); !errors.Is(err, s.wantErr) {
+					t.Errorf("send %d returned error %v, want %v", i, err, s.wantErr)
+				}
+			}
+
+			if diff := cmp.Diff(tc.wantSent, n.sent); diff != "" {
+				t.Errorf("unexpected sent Builds diff: (want- got+)\n%s", diff)
+			}
+			if got := testutil.ToFloat64(rateLimited.WithLabelValues(tc.wantOutcome)) - before; got != tc.wantCount {
+				t.Errorf("got %v rate limited notifications with outcome %q, want %v", got, tc.wantOutcome, tc.wantCount)
+			}
+		})
+	}
+}
+
+func TestRateLimitDelay(t *testing.T) {
+	n := new(rateRecordingNotifier)
+	rn := newRateLimitedNotifier(t, n, &RateLimitConfig{Rate: 10, Per: time.Second}, newRateLimiter())
+
+	start := time.Now()
+	for _, id := range []string{"f1", "f2", "f3"} {
+		if err := rn.SendNotification(context.Background(), failedBuild(id, "a")); err != nil {
+			t.Fatalf("SendNotification failed: %v", err)
+		}
+	}
+	// The burst is the rate, so the first 10 notifications are sent without delay.
+	if d := time.Since(start); d > 50*time.Millisecond {
+		t.Errorf("sends within the burst took %v", d)
+	}
+
+	rn.cfg = &RateLimitConfig{Rate: 10, Per: time.Second, Burst: 1}
+	start = time.Now()
+	for _, id := range []string{"f4", "f5"} {
+		if err := rn.SendNotification(context.Background(), failedBuild(id, "b")); err != nil {
+			t.Fatalf("SendNotification failed: %v", err)
+		}
+	}
+	if d := time.Since(start); d < 90*time.Millisecond {
+		t.Errorf("expected the second send beyond the burst to be delayed by about 100ms, took %v", d)
+	}
+	if diff := cmp.Diff([]string{"f1", "f2", "f3", "f4", "f5"}, n.sent); diff != "" {
+		t.Errorf("unexpected sent Builds diff: (want- got+)\n%s", diff)
+	}
+}
+
+func TestRateLimitSummaryFlush(t *testing.T) {
+	n := &rateRecordingNotifier{sump: make(chan *RateLimitSummary, 1)}
+	rn := newRateLimitedNotifier(t, n, &RateLimitConfig{Rate: 20, Per: time.Second, Burst: 1, Policy: rateLimitSummary}, newRateLimiter())
+
+	for _, id := range []string{"f1", "f2", "f3"} {
+		if err := rn.SendNotification(context.Background(), failedBuild(id, "a")); err != nil {
+			t.Fatalf("SendNotification failed: %v", err)
+		}
+	}
+
+	select {
+	case s := <-n.sump:
+		if diff := cmp.Diff([]string{"f2", "f3"}, s.BuildIDs); diff != "" || s.Count != 2 {
+			t.Errorf("unexpected summary (count %d) Build IDs diff: (want- got+)\n%s", s.Count, diff)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the summary to be sent")
+	}
+
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	if diff := cmp.Diff([]string{"f1", "f3+[f2 f3]"}, n.sent); diff != "" {
+		t.Errorf("unexpected sent Builds diff: (want- got+)\n%s", diff)
+	}
+}
+
+func TestRateLimitSummaryDrain(t *testing.T) {
+	rl := newRateLimiter()
+	n := new(rateRecordingNotifier)
+	rn := newRateLimitedNotifier(t, n, &RateLimitConfig{Rate: 1, Per: time.Hour, Policy: rateLimitSummary}, rl)
+	for _, id := range []string{"f1", "f2", "f3"} {
+		if err := rn.SendNotification(context.Background(), failedBuild(id, "a")); err != nil {
+			t.Fatalf("SendNotification failed: %v", err)
+		}
+	}
+
+	d := new(drainer)
+	d.onDrain(rl.drain)
+	if err := d.drain(context.Background()); err != nil {
+		t.Fatalf("drain failed: %v", err)
+	}
+	// The summary is sent on drain rather than in an hour, and its timer is stopped.
+	rl.buckets["a"].flush()
+
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	if diff := cmp.Diff([]string{"f1", "f3+[f2 f3]"}, n.sent); diff != "" {
+		t.Errorf("unexpected sent Builds diff: (want- got+)\n%s", diff)
+	}
+	if b := rl.buckets["a"]; b.timer != nil {
+		t.Error("expected the summary timer to be stopped after draining")
+	}
+}
@@ -0,0 +1,340 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifiers
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	cbpb "cloud.google.com/go/cloudbuild/apiv1/v2/cloudbuildpb"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v2"
+)
+
+// configLoader fetches a Config and its templates and sets up a notifier from them.
+type configLoader struct {
+	// notifier is never set up itself; every load sets up a copy of it.
+	notifier Notifier
+	// path is the location of the Config (see configSource). It is empty when the Config is given inline.
+	path string
+	// inline is the Config YAML itself, if it was not given by path.
+	inline string
+	src    *configSource
+	sg     *cachingSecretGetter
+	// rl rate limits the notifications of every loaded notifier (if their Config has a rate limit). It may be nil.
+	rl *rateLimiter
+	// sch holds the notifications that every loaded notifier deferred during quiet hours. It may be nil.
+	sch *scheduler
+}
+
+// loadedNotifier is a notifier that was set up from a given Config.
+type loadedNotifier struct {
+	cfg      *Config
+	tmpls    []string
+	notifier Notifier
+	// secrets are the secrets (by resource name) that the notifier was set up with.
+	secrets map[string]string
+	// fingerprint is a hash of the Config and templates that the notifier was set up with.
+	fingerprint [sha256.Size]byte
+	loadedAt    time.Time
+}
+
+// fetch gets, validates, and fingerprints the Config and its templates.
+func (c *configLoader) fetch(ctx context.Context) (*Config, []string, [sha256.Size]byte, error) {
+	var fp [sha256.Size]byte
+
+	cfg, err := c.getConfig(ctx)
+	if err != nil {
+		return nil, nil, fp, err
+	}
+
+	if err := validateConfig(cfg); err != nil {
+		return nil, nil, fp, fmt.Errorf("got invalid config from %s: %w", c.location(), err)
+	}
+	Debugf(ctx, "got config from %s: %+v\n", c.location(), cfg)
+
+	tmpls, err := parseTemplates(ctx, cfg, c.src, c.path)
+	if err != nil {
+		return nil, nil, fp, err
+	}
+
+	out, err := yaml.Marshal(cfg)
+	if err != nil {
+		return nil, nil, fp, fmt.Errorf("failed to re-encode config YAML: %w", err)
+	}
+	h := sha256.New()
+	h.Write(out)
+	for _, t := range tmpls {
+		h.Write([]byte{0})
+		h.Write([]byte(t))
+	}
+	copy(fp[:], h.Sum(nil))
+
+	return cfg, tmpls, fp, nil
+}
+
+func (c *configLoader) getConfig(ctx context.Context) (*Config, error) {
+	if c.path == "" {
+		cfg, err := decodeConfig(strings.NewReader(c.inline))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse inline configuration YAML: %w", err)
+		}
+		return cfg, nil
+	}
+
+	cfg, err := c.src.getConfig(ctx, c.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config from %q: %w", c.path, err)
+	}
+	return cfg, nil
+}
+
+// location describes where the Config comes from for logs and errors.
+func (c *configLoader) location() string {
+	if c.path == "" {
+		return "CONFIG_YAML"
+	}
+	return fmt.Sprintf("path %q", c.path)
+}
+
+// load fetches the Config and sets up a fresh copy of the notifier with it.
+func (c *configLoader) load(ctx context.Context) (*loadedNotifier, error) {
+	cfg, tmpls, fp, err := c.fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return c.setUp(ctx, cfg, tmpls, fp)
+}
+
+// setUp sets up a fresh copy of the notifier with the given (fetched) Config and templates.
+func (c *configLoader) setUp(ctx context.Context, cfg *Config, tmpls []string, fp [sha256.Size]byte) (*loadedNotifier, error) {
+	rsg := &recordingSecretGetter{sg: c.sg, secrets: map[string]string{}}
+	n, err := setUpNotifier(ctx, cloneNotifier(c.notifier), cfg, tmpls, rsg, c.rl, c.sch)
+	if err != nil {
+		return nil, err
+	}
+	return &loadedNotifier{cfg: cfg, tmpls: tmpls, notifier: n, secrets: rsg.secrets, fingerprint: fp, loadedAt: time.Now()}, nil
+}
+
+// secretNames returns the resource names of the secrets that the notifier was set up with.
+func (l *loadedNotifier) secretNames() []string {
+	var names []string
+	for name := range l.secrets {
+		names = append(names, name)
+	}
+	return names
+}
+
+// reloadingNotifier is a Notifier that swaps in a newly set up notifier whenever its Config, one of its templates, or
+// one of its secrets changes. A Config that fails to load never replaces the last good one.
+//
+// Secrets are checked before a send once they expire from the loader's cache, and right away when a send fails with
+// ErrUnauthorized. The Config and templates are only checked when watched.
+//
+// Only the notification routes (and the secrets they use) are reloaded; changes to the receiver settings in the
+// Config (e.g. `retry`, `deadLetter`, and `dedupe`) take effect on the next restart.
+type reloadingNotifier struct {
+	loader  *configLoader
+	current atomic.Pointer[loadedNotifier]
+	// mtx serializes swaps of current.
+	mtx sync.Mutex
+
+	// The errors of the latest attempts to fetch the Config, set up the notifier, and refresh its secrets, which are
+	// reported by `/readyz`. They are guarded by statusMtx rather than mtx so that checking them never waits on a load.
+	statusMtx  sync.Mutex
+	configErr  error
+	setupErr   error
+	secretsErr error
+}
+
+func newReloadingNotifier(loader *configLoader, initial *loadedNotifier) *reloadingNotifier {
+	r := &reloadingNotifier{loader: loader}
+	r.current.Store(initial)
+	return r
+}
+
+// SetUp is a no-op since the reloadingNotifier sets up its notifiers through its configLoader.
+func (r *reloadingNotifier) SetUp(_ context.Context, _ *Config, _ string, _ SecretGetter, _ BindingResolver) error {
+	return nil
+}
+
+// SendNotification sends the given Build with the most recently loaded notifier.
+// If the send fails with ErrUnauthorized and refreshing the secrets changes any of them, the send is retried once, and
+// with multiple routes only to the routes that failed with ErrUnauthorized.
+func (r *reloadingNotifier) SendNotification(ctx context.Context, build *cbpb.Build) error {
+	if r.loader.sg.expired(r.current.Load().secretNames()) {
+		if _, err := r.refreshSecrets(ctx, false); err != nil {
+			Warningf(ctx, "failed to refresh expired secrets, keeping the current ones: %v", err)
+		}
+	}
+
+	// Notifiers may modify the Build (e.g. adding UTM params to the log URL), so keep a copy for the retry.
+	retry := proto.Clone(build).(*cbpb.Build)
+	err := r.current.Load().notifier.SendNotification(ctx, build)
+	if !errors.Is(err, ErrUnauthorized) {
+		return err
+	}
+
+	Warningf(ctx, "notification was unauthorized, refreshing secrets: %v", err)
+	swapped, rerr := r.refreshSecrets(ctx, true)
+	if rerr != nil {
+		return errors.Join(err, fmt.Errorf("failed to refresh secrets: %w", rerr))
+	}
+	if !swapped {
+		// Retrying with the same secrets would fail the same way.
+		return err
+	}
+
+	// The other routes were either delivered or failed for other reasons, which new secrets do not fix.
+	var errs []error
+	if routes, others, ok := unauthorizedRoutes(err); ok {
+		ctx, errs = withOnlyRoutes(ctx, routes), others
+	}
+	return errors.Join(append(errs, r.current.Load().notifier.SendNotification(ctx, retry))...)
+}
+
+// unauthorizedRoutes splits the error of a multiNotifier into the routes that failed with ErrUnauthorized and the
+// errors of the other routes. It returns false if the error is not that of a multiNotifier.
+func unauthorizedRoutes(err error) (map[int]bool, []error, bool) {
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return nil, nil, false
+	}
+
+	routes := map[int]bool{}
+	var others []error
+	for _, e := range joined.Unwrap() {
+		var re *routeError
+		if !errors.As(e, &re) {
+			return nil, nil, false
+		}
+		if errors.Is(re.err, ErrUnauthorized) {
+			routes[re.route] = true
+		} else {
+			others = append(others, e)
+		}
+	}
+	return routes, others, true
+}
+
+// refreshSecrets fetches the secrets that the current notifier was set up with, from the cache unless force is true,
+// and sets the notifier up again if any of them changed.
+// It returns true iff the notifier was swapped.
+func (r *reloadingNotifier) refreshSecrets(ctx context.Context, force bool) (bool, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	cur := r.current.Load()
+	changed := false
+	for name, value := range cur.secrets {
+		get := r.loader.sg.GetSecret
+		if force {
+			get = r.loader.sg.refresh
+		}
+		v, err := get(ctx, name)
+		if err != nil {
+			err = fmt.Errorf("failed to get secret %q: %w", name, err)
+			r.setStatus(&r.secretsErr, err)
+			return false, err
+		}
+		if v != value {
+			Infof(ctx, "secret %q changed", name)
+			changed = true
+		}
+	}
+	r.setStatus(&r.secretsErr, nil)
+	if !changed {
+		return false, nil
+	}
+
+	ld, err := r.loader.setUp(ctx, cur.cfg, cur.tmpls, cur.fingerprint)
+	r.setStatus(&r.setupErr, err)
+	if err != nil {
+		return false, err
+	}
+	r.current.Store(ld)
+	return true, nil
+}
+
+// reload loads the Config and swaps in the resulting notifier if anything changed.
+// It returns true iff the notifier was swapped.
+func (r *reloadingNotifier) reload(ctx context.Context) (bool, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	cfg, tmpls, fp, err := r.loader.fetch(ctx)
+	r.setStatus(&r.configErr, err)
+	if err != nil {
+		return false, err
+	}
+	if fp == r.current.Load().fingerprint {
+		Debugf(ctx, "config from %s is unchanged", r.loader.location())
+		return false, nil
+	}
+
+	ld, err := r.loader.setUp(ctx, cfg, tmpls, fp)
+	r.setStatus(&r.setupErr, err)
+	if err != nil {
+		return false, err
+	}
+
+	r.current.Store(ld)
+	return true, nil
+}
+
+// watch reloads the Config every interval until the context is done.
+func (r *reloadingNotifier) watch(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+
+		swapped, err := r.reload(ctx)
+		if err != nil {
+			configLoadFailures.Inc()
+			Errorf(ctx, "failed to reload config from %s, keeping the last good one: %v", r.loader.location(), err)
+			continue
+		}
+		if swapped {
+			Infof(ctx, "reloaded config from %s", r.loader.location())
+		}
+	}
+}
+
+// setStatus records the error (or success, if nil) of the latest attempt at a load step.
+func (r *reloadingNotifier) setStatus(step *error, err error) {
+	r.statusMtx.Lock()
+	defer r.statusMtx.Unlock()
+	*step = err
+}
+
+// CheckHealth checks the health of the most recently loaded notifier if it implements HealthChecker.
+func (r *reloadingNotifier) CheckHealth(ctx context.Context) error {
+	if hc, ok := r.current.Load().notifier.(HealthChecker); ok {
+		return hc.CheckHealth(ctx)
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifiers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	cbpb "cloud.google.com/go/cloudbuild/apiv1/v2/cloudbuildpb"
+)
+
+const reloadConfigPath = "gs://bucket/config.yaml"
+
+func reloadConfigYAML(apiVersion, filter string) string {
+	return fmt.Sprintf(`
+apiVersion: %s
+kind: TestNotifier
+spec:
+  notification:
+    filter: %s
+    template:
+      type: golang
+      uri: gs://bucket/template
+`, apiVersion, filter)
+}
+
+// setUpCountingNotifier records the filter and template that it was set up with and fails to set up on a `bad` filter.
+type setUpCountingNotifier struct {
+	setUps *int // Shared between copies of the notifier.
+	filter string
+	tmpl   string
+}
+
+func (s *setUpCountingNotifier) SetUp(_ context.Context, cfg *Config, tmpl string, _ SecretGetter, _ BindingResolver) error {
+	*s.setUps++
+	if cfg.Spec.Notification.Filter == "bad" {
+		return errors.New("bad filter")
+	}
+	s.filter = cfg.Spec.Notification.Filter
+	s.tmpl = tmpl
+	return nil
+}
+
+func (s *setUpCountingNotifier) SendNotification(_ context.Context, _ *cbpb.Build) error {
+	return nil
+}
+
+func TestReloadingNotifier(t *testing.T) {
+	ctx := context.Background()
+	grf := &fakeGCSReaderFactory{
+		data: map[string]string{
+			reloadConfigPath:       reloadConfigYAML("cloud-build-notifiers/v1", "v1"),
+			"gs://bucket/template": "{{.Build.Id}}",
+		},
+	}
+	var setUps int
+	orig := &setUpCountingNotifier{setUps: &setUps}
+	cl := &configLoader{notifier: orig, path: reloadConfigPath, src: &configSource{grf: grf}, sg: newCachingSecretGetter(new(setupCheckSecretGetter), 0)}
+
+	ld, err := cl.load(ctx)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	rn := newReloadingNotifier(cl, ld)
+
+	current := func() *setUpCountingNotifier {
+		return rn.current.Load().notifier.(*setUpCountingNotifier)
+	}
+
+	for _, step := range []struct {
+		name        string
+		config      string
+		template    string
+		wantSwapped bool
+		wantErr     bool
+		wantFilter  string
+		wantTmpl    string
+		wantSetUps  int
+	}{
+		{
+			name:       "unchanged",
+			config:     reloadConfigYAML("cloud-build-notifiers/v1", "v1"),
+			template:   "{{.Build.Id}}",
+			wantFilter: "v1",
+			wantTmpl:   "{{.Build.Id}}",
+			wantSetUps: 1,
+		}, {
+			name:        "changed filter",
+			config:      reloadConfigYAML("cloud-build-notifiers/v1", "v2"),
+			template:    "{{.Build.Id}}",
+			wantSwapped: true,
+			wantFilter:  "v2",
+			wantTmpl:    "{{.Build.Id}}",
+			wantSetUps:  2,
+		}, {
+			name:        "changed template",
+			config:      reloadConfigYAML("cloud-build-notifiers/v1", "v2"),
+			template:    "{{.Build.Status}}",
+			wantSwapped: true,
+			wantFilter:  "v2",
+			wantTmpl:    "{{.Build.Status}}",
+			wantSetUps:  3,
+		}, {
+			name:       "invalid config",
+			config:     reloadConfigYAML("bad-api-version", "v3"),
+			template:   "{{.Build.Status}}",
+			wantErr:    true,
+			wantFilter: "v2",
+			wantTmpl:   "{{.Build.Status}}",
+			wantSetUps: 3,
+		}, {
+			name:       "failed SetUp",
+			config:     reloadConfigYAML("cloud-build-notifiers/v1", "bad"),
+			template:   "{{.Build.Status}}",
+			wantErr:    true,
+			wantFilter: "v2",
+			wantTmpl:   "{{.Build.Status}}",
+			wantSetUps: 4,
+		},
+	} {
+		grf.data[reloadConfigPath] = step.config
+		grf.data["gs://bucket/template"] = step.template
+
+		swapped, err := rn.reload(ctx)
+		if (err != nil) != step.wantErr {
+			t.Errorf("%s: reload returned error %v, want error: %v", step.name, err, step.wantErr)
+		}
+		if swapped != step.wantSwapped {
+			t.Errorf("%s: reload swapped = %v, want %v", step.name, swapped, step.wantSwapped)
+		}
+		if got := current(); got.filter != step.wantFilter || got.tmpl != step.wantTmpl {
+			t.Errorf("%s: current notifier has (filter=%q, template=%q), want (filter=%q, template=%q)", step.name, got.filter, got.tmpl, step.wantFilter, step.wantTmpl)
+		}
+		if setUps != step.wantSetUps {
+			t.Errorf("%s: got %d SetUp calls, want %d", step.name, setUps, step.wantSetUps)
+		}
+	}
+
+	if orig.filter != "" {
+		t.Errorf("original notifier was set up with filter %q, want it to never be set up", orig.filter)
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifiers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	cbpb "cloud.google.com/go/cloudbuild/apiv1/v2/cloudbuildpb"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v2"
+)
+
+// renderBuildID is the ID given to rendered Builds that have none, since some notifiers (and templates) expect one.
+const renderBuildID = "00000000-0000-0000-0000-000000000000"
+
+// Renderer is an optional interface for Notifiers that can render the payload of a notification (e.g. the body of a
+// webhook request or an email message) without delivering it. It backs the `-render` flag.
+type Renderer interface {
+	// Render returns the payload that SendNotification would deliver for the given Build, regardless of the filter.
+	Render(context.Context, *cbpb.Build) ([]byte, error)
+}
+
+// render sets up the given notifier from the Config at cfgPath (with the template at tmplPath, if any, replacing
+// every route's template) and writes the payload of every route for the Build at buildPath to w. Secrets are faked
+// out like in the setup check, and nothing is sent.
+func render(ctx context.Context, notifier Notifier, cfgPath, tmplPath, buildPath string, w io.Writer) error {
+	sc := new(lazyGCSClient)
+	defer sc.Close()
+	cl := &configLoader{notifier: notifier, path: cfgPath, src: &configSource{grf: &actualGCSReaderFactory{sc}}}
+	cfg, tmpls, _, err := cl.fetch(ctx)
+	if err != nil {
+		return err
+	}
+
+	if tmplPath != "" {
+		b, err := os.ReadFile(tmplPath)
+		if err != nil {
+			return fmt.Errorf("failed to read template %q: %w", tmplPath, err)
+		}
+		if err := validateTemplate(string(b)); err != nil {
+			return fmt.Errorf("got invalid template from path %q: %w", tmplPath, err)
+		}
+		for i := range tmpls {
+			tmpls[i] = string(b)
+		}
+	}
+
+	build, err := readBuild(buildPath)
+	if err != nil {
+		return err
+	}
+	if build.GetId() == "" {
+		Infof(ctx, "giving the Build from %q the ID %q since it has none", buildPath, renderBuildID)
+		build.Id = renderBuildID
+	}
+
+	// Notifiers that set up their destination in SetUp (e.g. the BigQuery notifier, which creates its table) must not
+	// touch it, so they are set up in dry-run mode.
+	if dryRun == nil {
+		dryRun = new(logDeliverySink)
+		defer func() { dryRun = nil }()
+	}
+	n, err := setUpNotifier(ctx, notifier, cfg, tmpls, new(setupCheckSecretGetter), nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to set up notifier for rendering: %w", err)
+	}
+	routes := routeNotifiers(n)
+
+	for i, route := range cfg.Spec.Routes() {
+		r, ok := routes[i].(Renderer)
+		if !ok {
+			return fmt.Errorf("notifier %T does not support rendering", routes[i])
+		}
+
+		filter, err := MakeCELPredicate(route.Filter)
+		if err != nil {
+			return fmt.Errorf("failed to make the filter of notification route %d: %w", i, err)
+		}
+		fctx := ctx
+		if br, err := newResolver(routeConfig(cfg, route)); err == nil {
+			// The filter can refer to the params. If they fail to resolve, the filter fails to evaluate (see below).
+			params, _ := br.Resolve(ctx, new(setupCheckSecretGetter), build)
+			fctx = withFilterParams(ctx, params)
+		}
+		if match, err := filter.eval(fctx, build); err != nil {
+			Warningf(ctx, "notification route %d: %v", i, err)
+		} else if !match {
+			Warningf(ctx, "notification route %d: the filter does not match the Build, so nothing would be sent", i)
+		}
+
+		// Notifiers may modify the Build, so every route gets its own copy.
+		payload, err := r.Render(ctx, proto.Clone(build).(*cbpb.Build))
+		if err != nil {
+			return fmt.Errorf("failed to render notification route %d: %w", i, err)
+		}
+		if len(routes) > 1 {
+			fmt.Fprintf(w, "--- notification route %d ---\n", i)
+		}
+		if _, err := w.Write(payload); err != nil {
+			return fmt.Errorf("failed to write payload: %w", err)
+		}
+		if len(payload) == 0 || payload[len(payload)-1] != '\n' {
+			fmt.Fprintln(w)
+		}
+	}
+	return nil
+}
+
+// readBuild reads a Build from a YAML or JSON file (e.g. a `cloudbuild.yaml` or the output of
+// `gcloud builds describe --format=json`). Unknown fields are ignored.
+func readBuild(path string) (*cbpb.Build, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Build %q: %w", path, err)
+	}
+	// JSON is YAML, so both go through the YAML decoder and then through protojson for the proto field names.
+	var v interface{}
+	if err := yaml.Unmarshal(b, &v); err != nil {
+		return nil, fmt.Errorf("failed to decode Build %q: %w", path, err)
+	}
+	j, err := json.Marshal(jsonValue(v))
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert Build %q to JSON: %w", path, err)
+	}
+
+	build := new(cbpb.Build)
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(j, build); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal Build %q: %w", path, err)
+	}
+	return build, nil
+}
+
+// jsonValue converts the maps that the YAML decoder returns (which have interface{} keys) to JSON objects.
+func jsonValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, e := range t {
+			m[fmt.Sprint(k)] = jsonValue(e)
+		}
+		return m
+	case []interface{}:
+		for i, e := range t {
+			t[i] = jsonValue(e)
+		}
+		return t
+	default:
+		return v
+	}
+}
@@ -0,0 +1,170 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifiers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+
+	cbpb "cloud.google.com/go/cloudbuild/apiv1/v2/cloudbuildpb"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+)
+
+// renderingNotifier renders its template with the Build and the `greeting` delivery field, and never sends anything.
+type renderingNotifier struct {
+	tmpl     *template.Template
+	greeting string
+	// requireDryRun makes SetUp fail outside of dry-run mode.
+	requireDryRun bool
+}
+
+func (r *renderingNotifier) SetUp(_ context.Context, cfg *Config, tmpl string, _ SecretGetter, _ BindingResolver) error {
+	if r.requireDryRun && !DryRun() {
+		return errors.New("rendering must set up notifiers in dry-run mode")
+	}
+	t, err := template.New("").Funcs(TemplateFuncs()).Parse(tmpl)
+	if err != nil {
+		return err
+	}
+	r.tmpl = t
+	r.greeting, _ = cfg.Spec.Notification.Delivery["greeting"].(string)
+	return nil
+}
+
+func (r *renderingNotifier) SendNotification(_ context.Context, _ *cbpb.Build) error {
+	return errors.New("rendering must not send notifications")
+}
+
+func (r *renderingNotifier) Render(_ context.Context, build *cbpb.Build) ([]byte, error) {
+	var b bytes.Buffer
+	if err := r.tmpl.Execute(&b, &TemplateView{Build: &BuildView{Build: build}, Params: map[string]string{"greeting": r.greeting}}); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write %q: %v", path, err)
+	}
+	return path
+}
+
+func TestRender(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := writeFile(t, dir, "config.yaml", `
+apiVersion: cloud-build-notifiers/v1
+kind: RenderingNotifier
+spec:
+  notifications:
+  - filter: build.status == Build.Status.FAILURE
+    delivery:
+      greeting: Oh no
+    template:
+      type: golang
+      uri: failure.tmpl
+  - filter: build.status == Build.Status.SUCCESS
+    delivery:
+      greeting: Yay
+    template:
+      type: golang
+      content: '{"text": {{toJson (printf "%s, %s" .Params.greeting .Build.Id)}}}'
+`)
+	writeFile(t, dir, "failure.tmpl", `{{.Params.greeting}}: {{.Build.Id}} {{.Build.Status}}`)
+	buildPath := writeFile(t, dir, "build.yaml", "id: some-build-id\nstatus: FAILURE\n")
+	tmplPath := writeFile(t, dir, "local.tmpl", "{{statusEmoji .Build.Status}} {{.Build.Id | truncate 5}}\n")
+
+	for _, tc := range []struct {
+		name     string
+		tmplPath string
+		want     string
+	}{{
+		name: "config templates",
+		want: "--- notification route 0 ---\nOh no: some-build-id FAILURE\n" +
+			"--- notification route 1 ---\n{\"text\": \"Yay, some-build-id\"}\n",
+	}, {
+		name:     "local template",
+		tmplPath: tmplPath,
+		want:     "--- notification route 0 ---\n❌ some…\n--- notification route 1 ---\n❌ some…\n",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			var out strings.Builder
+			if err := render(context.Background(), &renderingNotifier{requireDryRun: true}, cfgPath, tc.tmplPath, buildPath, &out); err != nil {
+				t.Fatalf("render failed: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, out.String()); diff != "" {
+				t.Errorf("unexpected output diff: (want- got+)\n%s", diff)
+			}
+			if DryRun() {
+				t.Error("DryRun() = true after rendering, want false")
+			}
+		})
+	}
+
+	if err := render(context.Background(), &setUpCountingNotifier{setUps: new(int)}, cfgPath, "", buildPath, new(strings.Builder)); err == nil {
+		t.Error("render succeeded for a notifier that is not a Renderer, want error")
+	}
+}
+
+func TestReadBuild(t *testing.T) {
+	dir := t.TempDir()
+	want := &cbpb.Build{
+		Id:            "some-build-id",
+		Status:        cbpb.Build_SUCCESS,
+		Steps:         []*cbpb.BuildStep{{Name: "busybox", Args: []string{"true"}}},
+		Substitutions: map[string]string{"BRANCH_NAME": "main"},
+	}
+
+	for _, tc := range []struct {
+		name    string
+		content string
+	}{{
+		name: "yaml",
+		content: `
+id: some-build-id
+status: SUCCESS
+steps:
+  - name: busybox
+    args: ["true"]
+substitutions:
+  BRANCH_NAME: main
+# Fields that are not part of a Build are ignored.
+unknownField:
+  nested: true
+`,
+	}, {
+		name:    "json",
+		content: `{"id": "some-build-id", "status": "SUCCESS", "steps": [{"name": "busybox", "args": ["true"]}], "substitutions": {"BRANCH_NAME": "main"}}`,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := readBuild(writeFile(t, dir, tc.name, tc.content))
+			if err != nil {
+				t.Fatalf("readBuild failed: %v", err)
+			}
+			if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+				t.Errorf("unexpected Build diff: (want- got+)\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifiers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+const (
+	defaultRetryBaseDelay = time.Second
+	defaultRetryMaxDelay  = 30 * time.Second
+)
+
+// RetryPolicy is the data container for configuring in-process retries of failed SendNotification calls.
+// Retries happen while the Pub/Sub push request is still open, so the total delay should stay well within the
+// subscription's ack deadline.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of SendNotification calls, including the first one.
+	MaxAttempts int `yaml:"maxAttempts"`
+	// BaseDelay is the delay before the first retry. It doubles on every following retry.
+	BaseDelay time.Duration `yaml:"baseDelay"`
+	// MaxDelay caps the delay between two attempts.
+	MaxDelay time.Duration `yaml:"maxDelay"`
+	// Jitter is the fraction (between 0 and 1) of each delay that is randomly subtracted from it.
+	Jitter float64 `yaml:"jitter"`
+}
+
+func validateRetryPolicy(r *RetryPolicy) error {
+	if r.MaxAttempts < 1 {
+		return fmt.Errorf("expected retry.maxAttempts to be positive, got %d", r.MaxAttempts)
+	}
+	if r.BaseDelay < 0 || r.MaxDelay < 0 {
+		return fmt.Errorf("expected retry delays to be non-negative, got baseDelay=%v and maxDelay=%v", r.BaseDelay, r.MaxDelay)
+	}
+	if r.Jitter < 0 || r.Jitter > 1 {
+		return fmt.Errorf("expected retry.jitter to be between 0 and 1, got %v", r.Jitter)
+	}
+	return nil
+}
+
+// delay returns how long to wait after the given (1-based) failed attempt, before any jitter is applied.
+func (r *RetryPolicy) delay(attempt int) time.Duration {
+	d, maxDelay := r.BaseDelay, r.MaxDelay
+	if d == 0 {
+		d = defaultRetryBaseDelay
+	}
+	if maxDelay == 0 {
+		maxDelay = defaultRetryMaxDelay
+	}
+
+	for i := 1; i < attempt && d < maxDelay; i++ {
+		d *= 2
+	}
+	if d > maxDelay {
+		d = maxDelay
+	}
+	return d
+}
+
+// do calls fn until it succeeds, the policy runs out of attempts, or the context is done.
+// It returns the number of attempts that were made and the last error. A nil policy makes a single attempt.
+func (r *RetryPolicy) do(ctx context.Context, fn func(context.Context) error) (int, error) {
+	maxAttempts := 1
+	if r != nil {
+		maxAttempts = r.MaxAttempts
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = fn(ctx); err == nil {
+			return attempt, nil
+		}
+		// A rate limited notification is not retried here, since its message is nacked for Pub/Sub to redeliver later.
+		if attempt >= maxAttempts || errors.Is(err, ErrRateLimited) {
+			return attempt, err
+		}
+
+		d := r.delay(attempt)
+		d -= time.Duration(r.Jitter * rand.Float64() * float64(d))
+		Warningf(ctx, "attempt %d of %d failed, retrying in %v: %v", attempt, maxAttempts, d, err)
+
+		t := time.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return attempt, errors.Join(err, ctx.Err())
+		case <-t.C:
+		}
+	}
+}
+
+// routeDeliveries records which notification routes of a multiNotifier a message was delivered to, so that retries
+// and redeliveries of the message are only sent to the routes that failed. Routes are identified by their index in the
+// Config. Within a single delivery the routes are remembered in memory; across redeliveries, they are remembered in
+// the dedupe store (if any) under the message's dedupe keys suffixed with the route.
+type routeDeliveries struct {
+	delivered map[int]bool
+	store     dedupeStore
+	keys      []string
+}
+
+type routeDeliveriesKey struct{}
+
+// withRouteDeliveries returns a context that records the routes that the message with the given dedupe keys is
+// delivered to. The store may be nil.
+func withRouteDeliveries(ctx context.Context, store dedupeStore, keys []string) context.Context {
+	return context.WithValue(ctx, routeDeliveriesKey{}, &routeDeliveries{delivered: map[int]bool{}, store: store, keys: keys})
+}
+
+// routeDeliveriesFromContext returns the routeDeliveries of the context, or nil if routes are not being recorded.
+func routeDeliveriesFromContext(ctx context.Context) *routeDeliveries {
+	d, _ := ctx.Value(routeDeliveriesKey{}).(*routeDeliveries)
+	return d
+}
+
+// done returns true iff the message was already delivered to the given route.
+func (d *routeDeliveries) done(ctx context.Context, route int) bool {
+	if d == nil {
+		return false
+	}
+	if d.delivered[route] {
+		return true
+	}
+	if d.store == nil {
+		return false
+	}
+	_, ok := containsAny(ctx, d.store, d.routeKeys(route))
+	return ok
+}
+
+// markDone records that the message was delivered to the given route.
+func (d *routeDeliveries) markDone(ctx context.Context, route int) {
+	if d == nil {
+		return
+	}
+	d.delivered[route] = true
+	if d.store != nil {
+		addAll(ctx, d.store, d.routeKeys(route))
+	}
+}
+
+func (d *routeDeliveries) routeKeys(route int) []string {
+	keys := make([]string, 0, len(d.keys))
+	for _, k := range d.keys {
+		keys = append(keys, fmt.Sprintf("%s/route/%d", k, route))
+	}
+	return keys
+}
@@ -0,0 +1,135 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifiers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	r := &RetryPolicy{MaxAttempts: 10, BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+	for attempt, want := range map[int]time.Duration{
+		1: time.Second,
+		2: 2 * time.Second,
+		3: 4 * time.Second,
+		4: 5 * time.Second,
+		9: 5 * time.Second,
+	} {
+		if got := r.delay(attempt); got != want {
+			t.Errorf("delay(%d) = %v, want %v", attempt, got, want)
+		}
+	}
+}
+
+func TestRetryPolicyDo(t *testing.T) {
+	ctx := context.Background()
+	errFlaky := errors.New("flaky")
+	for _, tc := range []struct {
+		name         string
+		policy       *RetryPolicy
+		failures     int
+		wantAttempts int
+		wantErr      bool
+	}{
+		{
+			name:         "nil policy success",
+			wantAttempts: 1,
+		}, {
+			name:         "nil policy failure",
+			failures:     1,
+			wantAttempts: 1,
+			wantErr:      true,
+		}, {
+			name:         "succeeds after retries",
+			policy:       &RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, Jitter: 0.5},
+			failures:     2,
+			wantAttempts: 3,
+		}, {
+			name:         "runs out of attempts",
+			policy:       &RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond},
+			failures:     5,
+			wantAttempts: 3,
+			wantErr:      true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			calls := 0
+			attempts, err := tc.policy.do(ctx, func(context.Context) error {
+				calls++
+				if calls <= tc.failures {
+					return errFlaky
+				}
+				return nil
+			})
+
+			if attempts != tc.wantAttempts || calls != tc.wantAttempts {
+				t.Errorf("do made %d attempts (%d calls), want %d", attempts, calls, tc.wantAttempts)
+			}
+			if tc.wantErr != (err != nil) {
+				t.Errorf("do returned error %v, want error: %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyDoCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &RetryPolicy{MaxAttempts: 5, BaseDelay: time.Hour}
+
+	attempts, err := r.do(ctx, func(context.Context) error {
+		cancel()
+		return errors.New("always fails")
+	})
+	if attempts != 1 {
+		t.Errorf("do made %d attempts, want 1", attempts)
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("do returned error %v, want one wrapping %v", err, context.Canceled)
+	}
+}
+
+func TestValidateRetryPolicy(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		policy  *RetryPolicy
+		wantErr bool
+	}{
+		{
+			name:   "valid",
+			policy: &RetryPolicy{MaxAttempts: 3, BaseDelay: time.Second, MaxDelay: time.Minute, Jitter: 0.2},
+		}, {
+			name:    "no attempts",
+			policy:  &RetryPolicy{},
+			wantErr: true,
+		}, {
+			name:    "negative delay",
+			policy:  &RetryPolicy{MaxAttempts: 3, BaseDelay: -time.Second},
+			wantErr: true,
+		}, {
+			name:    "jitter too large",
+			policy:  &RetryPolicy{MaxAttempts: 3, Jitter: 1.5},
+			wantErr: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := validateRetryPolicy(tc.policy); tc.wantErr != (err != nil) {
+				t.Errorf("validateRetryPolicy(%+v) = %v, want error: %v", tc.policy, err, tc.wantErr)
+			}
+		})
+	}
+}
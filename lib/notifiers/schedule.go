@@ -0,0 +1,309 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifiers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	// Embed the time zone database so that schedules work in images without one.
+	_ "time/tzdata"
+
+	cbpb "cloud.google.com/go/cloudbuild/apiv1/v2/cloudbuildpb"
+)
+
+const (
+	scheduleSuppress = "suppress"
+	scheduleDefer    = "defer"
+)
+
+// ScheduleConfig is the data container for the quiet hours of a notification route.
+type ScheduleConfig struct {
+	// TimeZone is the IANA time zone (e.g. `Europe/Berlin`) of the windows. Defaults to UTC.
+	TimeZone string `yaml:"timeZone"`
+	// Windows are the quiet hours. The first window that is open and matches a Build applies to it.
+	Windows []*ScheduleWindow `yaml:"windows"`
+}
+
+// ScheduleWindow is the data container for a single window of quiet hours.
+type ScheduleWindow struct {
+	// Days are the days of the week (e.g. `MON` or `Monday`) that the window starts on. Defaults to every day.
+	Days []string `yaml:"days"`
+	// Start and End are the times of day (`HH:MM`) that the window starts and ends at. A window whose End is not after
+	// its Start ends on the next day.
+	Start string `yaml:"start"`
+	End   string `yaml:"end"`
+	// Action is what happens to the notifications during the window: they are either dropped (`suppress`, the
+	// default) or sent as a digest once the window ends (`defer`).
+	Action string `yaml:"action"`
+	// Filter is an optional CEL filter that limits the window to the Builds that match it, e.g. to keep failures
+	// paging during the night.
+	Filter string `yaml:"filter"`
+}
+
+// Digest describes the notifications of a route that were deferred during its quiet hours.
+type Digest struct {
+	// Count is the number of deferred notifications. The Build of the digest is the most recent of them.
+	Count int
+	// BuildIDs are the IDs of the Builds of the deferred notifications.
+	BuildIDs []string
+	// Since is when the first of them was deferred.
+	Since time.Time
+}
+
+type digestKey struct{}
+
+// DigestFromContext returns the digest of the deferred notifications that is being sent, or nil if the notification
+// was not deferred.
+func DigestFromContext(ctx context.Context) *Digest {
+	d, _ := ctx.Value(digestKey{}).(*Digest)
+	return d
+}
+
+// schedule is a parsed ScheduleConfig.
+type schedule struct {
+	loc     *time.Location
+	windows []*window
+}
+
+type window struct {
+	days                [7]bool
+	startHour, startMin int
+	endHour, endMin     int
+	action              string
+	filter              *CELPredicate
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+func parseSchedule(cfg *ScheduleConfig) (*schedule, error) {
+	s := &schedule{loc: time.UTC}
+	if cfg.TimeZone != "" {
+		loc, err := time.LoadLocation(cfg.TimeZone)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load time zone %q: %w", cfg.TimeZone, err)
+		}
+		s.loc = loc
+	}
+	if len(cfg.Windows) == 0 {
+		return nil, errors.New("expected at least one window")
+	}
+
+	for i, cw := range cfg.Windows {
+		if cw == nil {
+			return nil, fmt.Errorf("expected window %d to be non-empty", i)
+		}
+		w := &window{action: cw.Action}
+		switch cw.Action {
+		case "":
+			w.action = scheduleSuppress
+		case scheduleSuppress, scheduleDefer:
+		default:
+			return nil, fmt.Errorf("expected window %d action %q to be one of `suppress` or `defer`", i, cw.Action)
+		}
+
+		if len(cw.Days) == 0 {
+			w.days = [7]bool{true, true, true, true, true, true, true}
+		}
+		for _, d := range cw.Days {
+			wd, ok := weekdays[strings.ToLower(d[:min(len(d), 3)])]
+			if !ok || (len(d) > 3 && !strings.EqualFold(d, wd.String())) {
+				return nil, fmt.Errorf("expected window %d day %q to be a day of the week", i, d)
+			}
+			w.days[wd] = true
+		}
+
+		var err error
+		if w.startHour, w.startMin, err = parseTimeOfDay(cw.Start); err != nil {
+			return nil, fmt.Errorf("got invalid window %d start: %w", i, err)
+		}
+		if w.endHour, w.endMin, err = parseTimeOfDay(cw.End); err != nil {
+			return nil, fmt.Errorf("got invalid window %d end: %w", i, err)
+		}
+
+		if cw.Filter != "" {
+			if w.filter, err = MakeCELPredicate(cw.Filter); err != nil {
+				return nil, fmt.Errorf("failed to make the filter of window %d: %w", i, err)
+			}
+		}
+		s.windows = append(s.windows, w)
+	}
+	return s, nil
+}
+
+// parseTimeOfDay parses an `HH:MM` time of day.
+func parseTimeOfDay(s string) (int, int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, 0, fmt.Errorf("expected %q to be a time of day (HH:MM): %w", s, err)
+	}
+	return t.Hour(), t.Minute(), nil
+}
+
+// quiet returns the window that applies to the given Build at the given time, and when that window ends. It returns
+// nil if no window applies.
+func (s *schedule) quiet(ctx context.Context, build *cbpb.Build, now time.Time) (*window, time.Time) {
+	lt := now.In(s.loc)
+	today := time.Date(lt.Year(), lt.Month(), lt.Day(), 0, 0, 0, 0, s.loc)
+	for _, w := range s.windows {
+		if w.filter != nil {
+			if match, err := w.filter.eval(ctx, build); err != nil || !match {
+				continue
+			}
+		}
+		// An open window started either today or (if it ends on the next day) yesterday.
+		for _, day := range []time.Time{today, today.AddDate(0, 0, -1)} {
+			if !w.days[day.Weekday()] {
+				continue
+			}
+			start := time.Date(day.Year(), day.Month(), day.Day(), w.startHour, w.startMin, 0, 0, s.loc)
+			end := time.Date(day.Year(), day.Month(), day.Day(), w.endHour, w.endMin, 0, 0, s.loc)
+			if !end.After(start) {
+				end = end.AddDate(0, 0, 1)
+			}
+			if !lt.Before(start) && lt.Before(end) {
+				return w, end
+			}
+		}
+	}
+	return nil, time.Time{}
+}
+
+// scheduler holds the deferred notifications of every route. It outlives Config reloads, so that reloading (or
+// rotating a secret) never loses a digest.
+type scheduler struct {
+	now func() time.Time
+
+	mtx     sync.Mutex
+	pending map[string]*pendingDigest
+}
+
+// pendingDigest is a Digest that has yet to be sent, and what it takes to send it.
+type pendingDigest struct {
+	digest *Digest
+	ctx    context.Context
+	build  *cbpb.Build
+	sender Notifier
+	// timer sends the digest once the window ends.
+	timer *time.Timer
+}
+
+func newScheduler() *scheduler {
+	return &scheduler{now: time.Now, pending: map[string]*pendingDigest{}}
+}
+
+// hold adds the given notification to the digest of the given route and, for the first one, schedules sending the
+// digest at the given time.
+func (s *scheduler) hold(ctx context.Context, n Notifier, key string, build *cbpb.Build, until time.Time) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	p, ok := s.pending[key]
+	if !ok {
+		p = &pendingDigest{digest: &Digest{Since: s.now()}}
+		s.pending[key] = p
+		p.timer = time.AfterFunc(until.Sub(s.now()), func() { s.flush(key) })
+	}
+	p.digest.Count++
+	p.digest.BuildIDs = append(p.digest.BuildIDs, build.GetId())
+	// Sending the digest must neither be canceled with the message nor lose its log fields.
+	p.ctx, p.build, p.sender = context.WithoutCancel(ctx), build, n
+}
+
+// flush sends the digest of the given route, if any.
+func (s *scheduler) flush(key string) {
+	s.mtx.Lock()
+	p, ok := s.pending[key]
+	delete(s.pending, key)
+	s.mtx.Unlock()
+	if !ok {
+		return
+	}
+	s.send(p.ctx, key, p)
+}
+
+// drain sends the digest of every route right away, even though their quiet hours have not ended yet, since the
+// notifier is shutting down and would otherwise lose them.
+func (s *scheduler) drain(ctx context.Context) {
+	s.mtx.Lock()
+	pending := s.pending
+	s.pending = map[string]*pendingDigest{}
+	s.mtx.Unlock()
+
+	for key, p := range pending {
+		p.timer.Stop()
+		pctx, cancel := detachedUntil(p.ctx, ctx)
+		s.send(pctx, key, p)
+		cancel()
+	}
+}
+
+func (s *scheduler) send(ctx context.Context, key string, p *pendingDigest) {
+	Infof(ctx, "sending a digest of %d notification(s) that were deferred during the quiet hours of %s", p.digest.Count, key)
+	if err := p.sender.SendNotification(context.WithValue(ctx, digestKey{}, p.digest), p.build); err != nil {
+		Errorf(ctx, "failed to send a digest of %d deferred notification(s) of %s: %v", p.digest.Count, key, err)
+	}
+}
+
+// scheduledNotifier is a Notifier that applies the quiet hours of a single route before passing its notifications on
+// to the route's notifier.
+type scheduledNotifier struct {
+	notifier Notifier
+	// filter is the route's filter. Only the Builds that match it are suppressed or deferred.
+	filter   *CELPredicate
+	schedule *schedule
+	sch      *scheduler
+	routeKey string
+}
+
+// SetUp is a no-op since the wrapped notifier is set up by setUpNotifier.
+func (s *scheduledNotifier) SetUp(_ context.Context, _ *Config, _ string, _ SecretGetter, _ BindingResolver) error {
+	return nil
+}
+
+func (s *scheduledNotifier) SendNotification(ctx context.Context, build *cbpb.Build) error {
+	if match, err := s.filter.eval(ctx, build); err != nil || !match {
+		// The notifier applies (and reports on) its own filter.
+		return s.notifier.SendNotification(ctx, build)
+	}
+
+	w, end := s.schedule.quiet(ctx, build, s.sch.now())
+	if w == nil {
+		return s.notifier.SendNotification(ctx, build)
+	}
+	if w.action == scheduleDefer {
+		quietHours.WithLabelValues(quietHoursDeferred).Inc()
+		Infof(ctx, "deferring notification for Build %q until the quiet hours of %s end at %v", build.GetId(), s.routeKey, end)
+		s.sch.hold(ctx, s.notifier, s.routeKey, build, end)
+		return nil
+	}
+	quietHours.WithLabelValues(quietHoursSuppressed).Inc()
+	Infof(ctx, "suppressing notification for Build %q during the quiet hours of %s", build.GetId(), s.routeKey)
+	return nil
+}
+
+// CheckHealth checks the health of the wrapped notifier if it implements HealthChecker.
+func (s *scheduledNotifier) CheckHealth(ctx context.Context) error {
+	if hc, ok := s.notifier.(HealthChecker); ok {
+		return hc.CheckHealth(ctx)
+	}
+	return nil
+}
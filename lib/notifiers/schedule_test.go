@@ -0,0 +1,274 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifiers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	cbpb "cloud.google.com/go/cloudbuild/apiv1/v2/cloudbuildpb"
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestScheduleQuiet(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("failed to load time zone: %v", err)
+	}
+	cfg := &ScheduleConfig{
+		TimeZone: "Europe/Berlin",
+		Windows: []*ScheduleWindow{{
+			// Weeknights, for successful Builds only.
+			Days:   []string{"MON", "tue", "Wednesday", "THU", "fri"},
+			Start:  "22:00",
+			End:    "07:00",
+			Filter: "build.status == Build.Status.SUCCESS",
+		}, {
+			Days:   []string{"SAT", "SUN"},
+			Start:  "00:00",
+			End:    "00:00",
+			Action: scheduleDefer,
+		}},
+	}
+	s, err := parseSchedule(cfg)
+	if err != nil {
+		t.Fatalf("parseSchedule failed: %v", err)
+	}
+
+	success := &cbpb.Build{Status: cbpb.Build_SUCCESS}
+	failure := &cbpb.Build{Status: cbpb.Build_FAILURE}
+	for _, tc := range []struct {
+		name       string
+		build      *cbpb.Build
+		now        time.Time
+		wantWindow int // -1 if no window applies.
+		wantEnd    time.Time
+	}{{
+		name:       "weekday afternoon",
+		build:      success,
+		now:        time.Date(2026, 1, 7, 15, 0, 0, 0, berlin), // Wednesday.
+		wantWindow: -1,
+	}, {
+		name:       "weeknight before midnight",
+		build:      success,
+		now:        time.Date(2026, 1, 7, 23, 0, 0, 0, berlin),
+		wantWindow: 0,
+		wantEnd:    time.Date(2026, 1, 8, 7, 0, 0, 0, berlin),
+	}, {
+		name:       "weeknight after midnight",
+		build:      success,
+		now:        time.Date(2026, 1, 8, 6, 59, 0, 0, berlin),
+		wantWindow: 0,
+		wantEnd:    time.Date(2026, 1, 8, 7, 0, 0, 0, berlin),
+	}, {
+		name:       "weeknight in UTC",
+		build:      success,
+		now:        time.Date(2026, 1, 7, 21, 30, 0, 0, time.UTC),
+		wantWindow: 0,
+		wantEnd:    time.Date(2026, 1, 8, 7, 0, 0, 0, berlin),
+	}, {
+		name:       "failures still page",
+		build:      failure,
+		now:        time.Date(2026, 1, 7, 23, 0, 0, 0, berlin),
+		wantWindow: -1,
+	}, {
+		name:       "Friday night continues into Saturday",
+		build:      success,
+		now:        time.Date(2026, 1, 10, 3, 0, 0, 0, berlin),
+		wantWindow: 0,
+		wantEnd:    time.Date(2026, 1, 10, 7, 0, 0, 0, berlin),
+	}, {
+		name:       "weekend",
+		build:      failure,
+		now:        time.Date(2026, 1, 11, 12, 0, 0, 0, berlin),
+		wantWindow: 1,
+		wantEnd:    time.Date(2026, 1, 12, 0, 0, 0, 0, berlin),
+	}, {
+		name:       "Sunday night does not continue into Monday",
+		build:      failure,
+		now:        time.Date(2026, 1, 12, 3, 0, 0, 0, berlin),
+		wantWindow: -1,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			w, end := s.quiet(context.Background(), tc.build, tc.now)
+			got := -1
+			for i, sw := range s.windows {
+				if sw == w {
+					got = i
+				}
+			}
+			if got != tc.wantWindow || !end.Equal(tc.wantEnd) {
+				t.Errorf("quiet returned window %d ending at %v, want window %d ending at %v", got, end, tc.wantWindow, tc.wantEnd)
+			}
+		})
+	}
+}
+
+func TestParseScheduleErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		cfg  *ScheduleConfig
+	}{{
+		name: "unknown time zone",
+		cfg:  &ScheduleConfig{TimeZone: "Mars/Olympus_Mons", Windows: []*ScheduleWindow{{Start: "22:00", End: "07:00"}}},
+	}, {
+		name: "no windows",
+		cfg:  &ScheduleConfig{},
+	}, {
+		name: "bad day",
+		cfg:  &ScheduleConfig{Windows: []*ScheduleWindow{{Days: []string{"Mondays"}, Start: "22:00", End: "07:00"}}},
+	}, {
+		name: "bad time",
+		cfg:  &ScheduleConfig{Windows: []*ScheduleWindow{{Start: "10pm", End: "07:00"}}},
+	}, {
+		name: "bad action",
+		cfg:  &ScheduleConfig{Windows: []*ScheduleWindow{{Start: "22:00", End: "07:00", Action: "delay"}}},
+	}, {
+		name: "bad filter",
+		cfg:  &ScheduleConfig{Windows: []*ScheduleWindow{{Start: "22:00", End: "07:00", Filter: "build.nope"}}},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := parseSchedule(tc.cfg); err == nil {
+				t.Error("parseSchedule succeeded, want error")
+			}
+		})
+	}
+}
+
+// digestRecordingNotifier records the Builds that it is asked to send, with the Build IDs of their digest.
+type digestRecordingNotifier struct {
+	mtx     sync.Mutex
+	sent    []string
+	digests chan *Digest
+}
+
+func (d *digestRecordingNotifier) SetUp(_ context.Context, _ *Config, _ string, _ SecretGetter, _ BindingResolver) error {
+	return nil
+}
+
+func (d *digestRecordingNotifier) SendNotification(ctx context.Context, build *cbpb.Build) error {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	sent := build.GetId()
+	if dg := DigestFromContext(ctx); dg != nil {
+		sent = fmt.Sprintf("%s+%v", sent, dg.BuildIDs)
+		d.digests <- dg
+	}
+	d.sent = append(d.sent, sent)
+	return nil
+}
+
+func TestScheduledNotifier(t *testing.T) {
+	filter, err := MakeCELPredicate("build.status != Build.Status.WORKING")
+	if err != nil {
+		t.Fatalf("MakeCELPredicate failed: %v", err)
+	}
+	s, err := parseSchedule(&ScheduleConfig{Windows: []*ScheduleWindow{{
+		Start:  "22:00",
+		End:    "07:00",
+		Action: scheduleDefer,
+		Filter: "build.status == Build.Status.SUCCESS",
+	}, {
+		Start: "22:00",
+		End:   "07:00",
+	}}})
+	if err != nil {
+		t.Fatalf("parseSchedule failed: %v", err)
+	}
+
+	// The clock stands just before the end of the window, so that the digest is sent right away.
+	now := time.Date(2026, 1, 7, 7, 0, 0, 0, time.UTC).Add(-50 * time.Millisecond)
+	sch := newScheduler()
+	sch.now = func() time.Time { return now }
+	n := &digestRecordingNotifier{digests: make(chan *Digest, 1)}
+	sn := &scheduledNotifier{notifier: n, filter: filter, schedule: s, sch: sch, routeKey: "route/0"}
+
+	before := map[string]float64{}
+	for _, o := range []string{quietHoursDeferred, quietHoursSuppressed} {
+		before[o] = testutil.ToFloat64(quietHours.WithLabelValues(o))
+	}
+	for _, b := range []*cbpb.Build{
+		{Id: "s1", Status: cbpb.Build_SUCCESS},
+		{Id: "f1", Status: cbpb.Build_FAILURE},
+		// Builds that do not match the route's filter are passed on as is.
+		{Id: "w1", Status: cbpb.Build_WORKING},
+		{Id: "s2", Status: cbpb.Build_SUCCESS},
+	} {
+		if err := sn.SendNotification(context.Background(), b); err != nil {
+			t.Fatalf("SendNotification failed: %v", err)
+		}
+	}
+
+	select {
+	case dg := <-n.digests:
+		if diff := cmp.Diff([]string{"s1", "s2"}, dg.BuildIDs); diff != "" || dg.Count != 2 || !dg.Since.Equal(now) {
+			t.Errorf("unexpected digest (count %d, since %v) Build IDs diff: (want- got+)\n%s", dg.Count, dg.Since, diff)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the digest to be sent")
+	}
+
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	if diff := cmp.Diff([]string{"w1", "s2+[s1 s2]"}, n.sent); diff != "" {
+		t.Errorf("unexpected sent Builds diff: (want- got+)\n%s", diff)
+	}
+	for o, want := range map[string]float64{quietHoursDeferred: 2, quietHoursSuppressed: 1} {
+		if got := testutil.ToFloat64(quietHours.WithLabelValues(o)) - before[o]; got != want {
+			t.Errorf("quiet_hours_notifications_total{outcome=%q} increased by %v, want %v", o, got, want)
+		}
+	}
+}
+
+func TestSchedulerDrain(t *testing.T) {
+	filter, err := MakeCELPredicate("true")
+	if err != nil {
+		t.Fatalf("MakeCELPredicate failed: %v", err)
+	}
+	s, err := parseSchedule(&ScheduleConfig{Windows: []*ScheduleWindow{{Start: "22:00", End: "07:00", Action: scheduleDefer}}})
+	if err != nil {
+		t.Fatalf("parseSchedule failed: %v", err)
+	}
+
+	// The window ends in eight hours, so only draining sends the digest.
+	now := time.Date(2026, 1, 7, 23, 0, 0, 0, time.UTC)
+	sch := newScheduler()
+	sch.now = func() time.Time { return now }
+	n := &digestRecordingNotifier{digests: make(chan *Digest, 1)}
+	sn := &scheduledNotifier{notifier: n, filter: filter, schedule: s, sch: sch, routeKey: "route/0"}
+	for _, id := range []string{"s1", "s2"} {
+		if err := sn.SendNotification(context.Background(), &cbpb.Build{Id: id, Status: cbpb.Build_SUCCESS}); err != nil {
+			t.Fatalf("SendNotification failed: %v", err)
+		}
+	}
+
+	d := new(drainer)
+	d.onDrain(sch.drain)
+	if err := d.drain(context.Background()); err != nil {
+		t.Fatalf("drain failed: %v", err)
+	}
+	// Once drained, the digest is no longer pending, so the end of the window sends nothing.
+	sch.flush("route/0")
+
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	if diff := cmp.Diff([]string{"s2+[s1 s2]"}, n.sent); diff != "" {
+		t.Errorf("unexpected sent Builds diff: (want- got+)\n%s", diff)
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifiers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const defaultVaultField = "value"
+
+// vaultRequestTimeout bounds how long a request to Vault may take, so that an unresponsive server cannot block SetUp
+// (or a delivery that waits for a secret) forever.
+const vaultRequestTimeout = 10 * time.Second
+
+// secretDispatcher is a SecretGetter that routes every secret to a backend by the scheme prefix of its resource name
+// (e.g. `env://`). Resource names without a scheme go to the default backend.
+type secretDispatcher struct {
+	backends map[string]SecretGetter // Scheme (e.g. "env") => backend.
+	fallback SecretGetter
+}
+
+// newSecretDispatcher returns a secretDispatcher for the `env://`, `file://`, `vault://`, and `sm://` schemes, with the
+// given Secret Manager as the default backend.
+func newSecretDispatcher(sm SecretGetter) *secretDispatcher {
+	vaultAddr, _ := GetEnv("VAULT_ADDR")
+	// Unlike GetEnv, os.Getenv does not log the token.
+	vaultToken := os.Getenv("VAULT_TOKEN")
+	vaultNamespace, _ := GetEnv("VAULT_NAMESPACE")
+	return &secretDispatcher{
+		backends: map[string]SecretGetter{
+			"env":   new(envSecretGetter),
+			"file":  new(fileSecretGetter),
+			"vault": &vaultSecretGetter{addr: vaultAddr, token: vaultToken, namespace: vaultNamespace},
+			"sm":    sm,
+		},
+		fallback: sm,
+	}
+}
+
+// GetSecret gets the named secret from the backend for the name's scheme, passing it the name without the scheme.
+func (d *secretDispatcher) GetSecret(ctx context.Context, name string) (string, error) {
+	scheme, rest, ok := strings.Cut(name, "://")
+	if !ok {
+		return d.fallback.GetSecret(ctx, name)
+	}
+
+	sg, ok := d.backends[scheme]
+	if !ok {
+		return "", fmt.Errorf("got unknown scheme %q for secret %q", scheme, name)
+	}
+	return sg.GetSecret(ctx, rest)
+}
+
+// envSecretGetter gets secrets from the environment variable with the given name.
+type envSecretGetter struct{}
+
+func (e *envSecretGetter) GetSecret(_ context.Context, name string) (string, error) {
+	v, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("expected secret environment variable %q to be set", name)
+	}
+	return v, nil
+}
+
+// fileSecretGetter gets secrets from the file at the given path, e.g. a mounted Kubernetes or Cloud Run secret volume.
+// Trailing newlines are removed.
+type fileSecretGetter struct{}
+
+func (f *fileSecretGetter) GetSecret(_ context.Context, path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file %q: %w", path, err)
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+// vaultSecretGetter gets secrets from HashiCorp Vault's KV secrets engine (v1 or v2) through its HTTP API.
+// Names are of the form `path/to/secret#field`, where the field defaults to `value`, e.g. `secret/data/slack#webhook`
+// for a KV v2 engine mounted at `secret/`.
+type vaultSecretGetter struct {
+	addr      string
+	token     string
+	namespace string
+	// client is used for requests to Vault. If nil, a client with a timeout of vaultRequestTimeout is used.
+	client *http.Client
+}
+
+func (v *vaultSecretGetter) httpClient() *http.Client {
+	if v.client == nil {
+		return &http.Client{Timeout: vaultRequestTimeout}
+	}
+	return v.client
+}
+
+func (v *vaultSecretGetter) GetSecret(ctx context.Context, name string) (string, error) {
+	if v.addr == "" {
+		return "", errors.New("expected VAULT_ADDR to be set for `vault://` secrets")
+	}
+
+	path, field, ok := strings.Cut(name, "#")
+	if !ok {
+		field = defaultVaultField
+	}
+	u := fmt.Sprintf("%s/v1/%s", strings.TrimSuffix(v.addr, "/"), strings.TrimPrefix(path, "/"))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create a new HTTP request for Vault: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+	if v.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.namespace)
+	}
+
+	resp, err := v.httpClient().Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to make HTTP request to Vault: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("got a non-OK response status %q (%d) from Vault for secret %q", resp.Status, resp.StatusCode, path)
+	}
+
+	var body struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode Vault response for secret %q: %w", path, err)
+	}
+
+	// KV v2 nests the secret's data (next to its metadata) in another `data` object.
+	data := body.Data
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		if _, ok := data["metadata"]; ok {
+			data = nested
+		}
+	}
+
+	val, ok := data[field]
+	if !ok {
+		return "", fmt.Errorf("expected Vault secret %q to have field %q", path, field)
+	}
+	s, ok := val.(string)
+	if !ok {
+		return "", fmt.Errorf("expected field %q of Vault secret %q to have a string value", field, path)
+	}
+	return s, nil
+}
@@ -0,0 +1,133 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifiers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// fakeVault is a stand-in for Vault's HTTP API with a KV v1 engine mounted at `kv/` and a KV v2 engine at `secret/`.
+func fakeVault(t *testing.T, token string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != token {
+			http.Error(w, `{"errors":["permission denied"]}`, http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/kv/slack":
+			fmt.Fprint(w, `{"data": {"value": "v1-webhook", "other": "v1-other"}}`)
+		case "/v1/secret/data/slack":
+			fmt.Fprint(w, `{"data": {"data": {"value": "v2-webhook", "number": 3}, "metadata": {"version": 2}}}`)
+		default:
+			http.Error(w, `{"errors":[]}`, http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestSecretDispatcher(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("TEST_SECRET_ENV", "from-env")
+	path := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(path, []byte("from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	vault := fakeVault(t, "vault-token")
+	sm := &fakeSecretStore{secrets: map[string]string{latestSecret: "from-sm"}}
+
+	d := newSecretDispatcher(sm)
+	d.backends["vault"] = &vaultSecretGetter{addr: vault.URL, token: "vault-token"}
+
+	for _, tc := range []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: latestSecret, want: "from-sm"},
+		{name: "sm://" + latestSecret, want: "from-sm"},
+		{name: "env://TEST_SECRET_ENV", want: "from-env"},
+		{name: "file://" + path, want: "from-file"},
+		{name: "vault://kv/slack", want: "v1-webhook"},
+		{name: "vault://kv/slack#other", want: "v1-other"},
+		{name: "vault://secret/data/slack", want: "v2-webhook"},
+		{name: "env://MISSING_TEST_SECRET_ENV", wantErr: true},
+		{name: "file://" + path + ".missing", wantErr: true},
+		{name: "vault://kv/missing", wantErr: true},
+		{name: "vault://kv/slack#missing", wantErr: true},
+		{name: "vault://secret/data/slack#number", wantErr: true},
+		{name: "s3://bucket/secret", wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := d.GetSecret(ctx, tc.name)
+			if err != nil {
+				if tc.wantErr {
+					t.Logf("got expected error: %v", err)
+					return
+				}
+				t.Fatalf("GetSecret(%q) got unexpected error: %v", tc.name, err)
+			}
+			if tc.wantErr {
+				t.Fatalf("GetSecret(%q) = %q, want an error", tc.name, got)
+			}
+			if got != tc.want {
+				t.Errorf("GetSecret(%q) = %q, want %q", tc.name, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestVaultSecretGetterErrors(t *testing.T) {
+	ctx := context.Background()
+	vault := fakeVault(t, "vault-token")
+	unblock := make(chan struct{})
+	hung := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-unblock
+	}))
+	defer hung.Close()
+	defer close(unblock)
+
+	if c := new(vaultSecretGetter).httpClient(); c.Timeout != vaultRequestTimeout {
+		t.Errorf("got default client timeout %v, want %v", c.Timeout, vaultRequestTimeout)
+	}
+
+	for _, tc := range []struct {
+		name string
+		v    *vaultSecretGetter
+	}{{
+		name: "no address",
+		v:    &vaultSecretGetter{token: "vault-token"},
+	}, {
+		name: "bad token",
+		v:    &vaultSecretGetter{addr: vault.URL, token: "bad-token"},
+	}, {
+		name: "unresponsive server",
+		v:    &vaultSecretGetter{addr: hung.URL, token: "vault-token", client: &http.Client{Timeout: 50 * time.Millisecond}},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := tc.v.GetSecret(ctx, "kv/slack"); err == nil {
+				t.Error("GetSecret unexpectedly succeeded")
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifiers
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"sync"
+	"time"
+)
+
+const defaultSecretCacheTTL = 10 * time.Minute
+
+// ErrUnauthorized should be wrapped by the errors that notifiers return from SendNotification when the destination
+// rejected their credentials (e.g. with an HTTP 401 or 403). The notifier's secrets are then refreshed and, if any of
+// them changed, the notification is sent once more.
+var ErrUnauthorized = errors.New("unauthorized")
+
+// pinnedSecretVersionPattern matches Secret Manager resource names of fixed secret versions, which never change.
+var pinnedSecretVersionPattern = regexp.MustCompile(`^(sm://)?projects/[^/]+/secrets/[^/]+/versions/[0-9]+$`)
+
+// cachingSecretGetter is a SecretGetter that caches the secrets of another SecretGetter.
+// Secrets expire after the TTL, except for pinned Secret Manager versions; mutable versions such as `latest` are
+// therefore re-resolved once they expire.
+type cachingSecretGetter struct {
+	sg SecretGetter
+	// ttl is how long secrets are cached. If non-positive, secrets never expire.
+	ttl time.Duration
+	now func() time.Time
+
+	mtx   sync.Mutex
+	cache map[string]*cachedSecret
+}
+
+type cachedSecret struct {
+	value   string
+	fetched time.Time
+}
+
+func newCachingSecretGetter(sg SecretGetter, ttl time.Duration) *cachingSecretGetter {
+	return &cachingSecretGetter{sg: sg, ttl: ttl, now: time.Now, cache: map[string]*cachedSecret{}}
+}
+
+// GetSecret returns the cached secret, fetching it first if it is missing or expired.
+func (c *cachingSecretGetter) GetSecret(ctx context.Context, name string) (string, error) {
+	c.mtx.Lock()
+	cs, ok := c.cache[name]
+	c.mtx.Unlock()
+
+	if ok && !c.isExpired(name, cs) {
+		return cs.value, nil
+	}
+	return c.refresh(ctx, name)
+}
+
+// refresh fetches the secret regardless of whether it is cached.
+// A failed fetch leaves any previously cached value in place.
+func (c *cachingSecretGetter) refresh(ctx context.Context, name string) (string, error) {
+	value, err := c.sg.GetSecret(ctx, name)
+	if err != nil {
+		secretFetchFailures.Inc()
+		return "", err
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.cache[name] = &cachedSecret{value: value, fetched: c.now()}
+	return value, nil
+}
+
+// expired returns true iff any of the given secrets is missing from the cache or has expired.
+func (c *cachingSecretGetter) expired(names []string) bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	for _, name := range names {
+		cs, ok := c.cache[name]
+		if !ok || c.isExpired(name, cs) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *cachingSecretGetter) isExpired(name string, cs *cachedSecret) bool {
+	if c.ttl <= 0 || pinnedSecretVersionPattern.MatchString(name) {
+		return false
+	}
+	return c.now().Sub(cs.fetched) >= c.ttl
+}
+
+// recordingSecretGetter is a SecretGetter that records every secret that it returns.
+type recordingSecretGetter struct {
+	sg      SecretGetter
+	secrets map[string]string
+}
+
+func (r *recordingSecretGetter) GetSecret(ctx context.Context, name string) (string, error) {
+	value, err := r.sg.GetSecret(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	r.secrets[name] = value
+	return value, nil
+}
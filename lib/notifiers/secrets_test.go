@@ -0,0 +1,243 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifiers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	cbpb "cloud.google.com/go/cloudbuild/apiv1/v2/cloudbuildpb"
+	"github.com/google/go-cmp/cmp"
+)
+
+const (
+	latestSecret = "projects/p/secrets/s/versions/latest"
+	pinnedSecret = "projects/p/secrets/s/versions/3"
+)
+
+// fakeSecretStore is a SecretGetter whose secrets can be rotated and that counts its fetches.
+type fakeSecretStore struct {
+	secrets map[string]string
+	fetches int
+}
+
+func (f *fakeSecretStore) GetSecret(_ context.Context, name string) (string, error) {
+	f.fetches++
+	s, ok := f.secrets[name]
+	if !ok {
+		return "", fmt.Errorf("no secret named %q", name)
+	}
+	return s, nil
+}
+
+func TestCachingSecretGetter(t *testing.T) {
+	ctx := context.Background()
+	store := &fakeSecretStore{secrets: map[string]string{latestSecret: "v1", pinnedSecret: "v3"}}
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := newCachingSecretGetter(store, time.Minute)
+	c.now = func() time.Time { return now }
+
+	get := func(name, want string, wantFetches int) {
+		t.Helper()
+		got, err := c.GetSecret(ctx, name)
+		if err != nil {
+			t.Fatalf("GetSecret(%q) failed: %v", name, err)
+		}
+		if got != want {
+			t.Errorf("GetSecret(%q) = %q, want %q", name, got, want)
+		}
+		if store.fetches != wantFetches {
+			t.Errorf("got %d fetches, want %d", store.fetches, wantFetches)
+		}
+	}
+
+	get(latestSecret, "v1", 1)
+	get(pinnedSecret, "v3", 2)
+
+	// Cached values are returned until they expire, even if the secret was rotated.
+	store.secrets[latestSecret] = "v2"
+	now = now.Add(30 * time.Second)
+	get(latestSecret, "v1", 2)
+	if c.expired([]string{latestSecret, pinnedSecret}) {
+		t.Error("expired() = true before the TTL, want false")
+	}
+
+	// `latest` is re-resolved once it expires, but pinned versions never expire.
+	now = now.Add(30 * time.Second)
+	if !c.expired([]string{latestSecret}) {
+		t.Error("expired() = false after the TTL, want true")
+	}
+	if c.expired([]string{pinnedSecret}) {
+		t.Error("expired() = true for a pinned version, want false")
+	}
+	get(latestSecret, "v2", 3)
+	get(pinnedSecret, "v3", 3)
+
+	if _, err := c.GetSecret(ctx, "projects/p/secrets/missing/versions/latest"); err == nil {
+		t.Error("GetSecret for a missing secret unexpectedly succeeded")
+	}
+}
+
+// secretAuthNotifier is set up with a secret token and fails to send with ErrUnauthorized unless its token starts with
+// `good`.
+type secretAuthNotifier struct {
+	token string
+	sends *int // Shared between copies of the notifier.
+}
+
+func (s *secretAuthNotifier) SetUp(ctx context.Context, _ *Config, _ string, sg SecretGetter, _ BindingResolver) error {
+	token, err := sg.GetSecret(ctx, latestSecret)
+	if err != nil {
+		return err
+	}
+	s.token = token
+	return nil
+}
+
+func (s *secretAuthNotifier) SendNotification(_ context.Context, _ *cbpb.Build) error {
+	*s.sends++
+	if !strings.HasPrefix(s.token, "good") {
+		return fmt.Errorf("token %q was rejected: %w", s.token, ErrUnauthorized)
+	}
+	return nil
+}
+
+func TestReloadingNotifierRefreshesSecrets(t *testing.T) {
+	ctx := context.Background()
+	grf := &fakeGCSReaderFactory{
+		data: map[string]string{
+			reloadConfigPath:       reloadConfigYAML("cloud-build-notifiers/v1", "v1"),
+			"gs://bucket/template": "{{.Build.Id}}",
+		},
+	}
+	store := &fakeSecretStore{secrets: map[string]string{latestSecret: "old"}}
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	sg := newCachingSecretGetter(store, time.Minute)
+	sg.now = func() time.Time { return now }
+
+	var sends int
+	cl := &configLoader{notifier: &secretAuthNotifier{sends: &sends}, path: reloadConfigPath, src: &configSource{grf: grf}, sg: sg}
+	ld, err := cl.load(ctx)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	rn := newReloadingNotifier(cl, ld)
+	token := func() string {
+		return rn.current.Load().notifier.(*secretAuthNotifier).token
+	}
+
+	// The secret was rotated and the destination rejects the old one, so it is refreshed and the send retried.
+	store.secrets[latestSecret] = "good"
+	if err := rn.SendNotification(ctx, new(cbpb.Build)); err != nil {
+		t.Errorf("SendNotification with a revoked secret failed: %v", err)
+	}
+	if sends != 2 {
+		t.Errorf("got %d sends, want 2", sends)
+	}
+	if token() != "good" {
+		t.Errorf("got token %q after refresh, want %q", token(), "good")
+	}
+
+	// An expired secret is re-resolved before sending.
+	store.secrets[latestSecret] = "good-too"
+	now = now.Add(time.Minute)
+	if err := rn.SendNotification(ctx, new(cbpb.Build)); err != nil {
+		t.Errorf("SendNotification with an expired secret failed: %v", err)
+	}
+	if token() != "good-too" {
+		t.Errorf("got token %q after expiry, want %q", token(), "good-too")
+	}
+
+	// If refreshing does not change the secret, the send is not retried.
+	rn.current.Load().notifier.(*secretAuthNotifier).token = "revoked"
+	sends = 0
+	if err := rn.SendNotification(ctx, new(cbpb.Build)); err == nil {
+		t.Error("SendNotification with a rejected secret unexpectedly succeeded")
+	}
+	if sends != 1 {
+		t.Errorf("got %d sends, want 1", sends)
+	}
+}
+
+// routeAuthNotifier behaves according to the filter of its route: `healthy` routes always succeed, `broken` routes
+// always fail, and `secret` routes fail with ErrUnauthorized unless their secret token starts with `good`.
+type routeAuthNotifier struct {
+	filter string
+	token  string
+	sends  map[string]int // Shared between copies of the notifier.
+}
+
+func (r *routeAuthNotifier) SetUp(ctx context.Context, cfg *Config, _ string, sg SecretGetter, _ BindingResolver) error {
+	r.filter = cfg.Spec.Notification.Filter
+	if r.filter != "secret" {
+		return nil
+	}
+	token, err := sg.GetSecret(ctx, latestSecret)
+	if err != nil {
+		return err
+	}
+	r.token = token
+	return nil
+}
+
+func (r *routeAuthNotifier) SendNotification(_ context.Context, _ *cbpb.Build) error {
+	r.sends[r.filter]++
+	switch {
+	case r.filter == "broken":
+		return errors.New("destination is down")
+	case r.filter == "secret" && !strings.HasPrefix(r.token, "good"):
+		return fmt.Errorf("token %q was rejected: %w", r.token, ErrUnauthorized)
+	}
+	return nil
+}
+
+func TestReloadingNotifierRetriesUnauthorizedRoutes(t *testing.T) {
+	ctx := context.Background()
+	store := &fakeSecretStore{secrets: map[string]string{latestSecret: "old"}}
+	sends := map[string]int{}
+	cl := &configLoader{
+		notifier: &routeAuthNotifier{sends: sends},
+		inline: `
+apiVersion: cloud-build-notifiers/v1
+kind: TestNotifier
+spec:
+  notifications:
+  - filter: healthy
+  - filter: secret
+  - filter: broken
+`,
+		src: new(configSource),
+		sg:  newCachingSecretGetter(store, time.Minute),
+	}
+	ld, err := cl.load(ctx)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	rn := newReloadingNotifier(cl, ld)
+
+	// The secret was rotated, so only the route that was rejected is sent again after the refresh.
+	store.secrets[latestSecret] = "good"
+	err = rn.SendNotification(ctx, new(cbpb.Build))
+	if err == nil || errors.Is(err, ErrUnauthorized) {
+		t.Errorf("SendNotification returned %v, want only the error of the broken route", err)
+	}
+	if diff := cmp.Diff(map[string]int{"healthy": 1, "secret": 2, "broken": 1}, sends); diff != "" {
+		t.Errorf("unexpected sends diff: (want- got+)\n%s", diff)
+	}
+}
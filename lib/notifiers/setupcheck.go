@@ -0,0 +1,231 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifiers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	cbpb "cloud.google.com/go/cloudbuild/apiv1/v2/cloudbuildpb"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v2"
+)
+
+// runSetupCheck decodes the Config from r, validates it, and sets up the notifier with it in a faked-out way. If
+// buildPatterns (a comma-separated list of paths or globs) is non-empty, the templates of the Config are fetched too,
+// and every route's filter, params, and template are checked against every matching sample Build, with a report
+// written to w. An error is returned if any of the checks failed.
+func runSetupCheck(ctx context.Context, notifier Notifier, r io.Reader, src *configSource, buildPatterns string, w io.Writer) error {
+	Debugf(ctx, "starting setup check")
+	cfg, err := decodeConfig(r)
+	if err != nil {
+		return fmt.Errorf("failed to decode YAML config from stdin: %w", err)
+	}
+
+	if out, err := yaml.Marshal(cfg); err != nil {
+		Warningf(ctx, "failed to re-encode config YAML: %v", err)
+	} else {
+		Debugf(ctx, "got re-encoded YAML from stdin:\n%s", string(out))
+	}
+
+	if err := validateConfig(cfg); err != nil {
+		return fmt.Errorf("failed to validate config during setup check: %w", err)
+	}
+
+	// Notifiers compile their filters in SetUp, but not all of them report which route's filter is invalid.
+	for i, route := range cfg.Spec.Routes() {
+		if _, err := MakeCELPredicate(route.Filter); err != nil {
+			return fmt.Errorf("got invalid filter for notification route %d during setup check: %w", i, err)
+		}
+	}
+
+	// Without sample Builds, templates are not fetched, so every route gets an empty one.
+	tmpls := make([]string, len(cfg.Spec.Routes()))
+	var paths []string
+	if buildPatterns != "" {
+		if paths, err = expandBuildPatterns(buildPatterns); err != nil {
+			return err
+		}
+		// The Config comes from stdin, so relative template URIs are resolved against the working directory.
+		if tmpls, err = parseTemplates(ctx, cfg, src, ""); err != nil {
+			return fmt.Errorf("failed to get templates during setup check: %w", err)
+		}
+	}
+
+	sg := new(setupCheckSecretGetter)
+	n, err := setUpNotifier(ctx, notifier, cfg, tmpls, sg, nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to set up notifier during setup check: %w", err)
+	}
+
+	if len(paths) > 0 {
+		if err := checkSamples(ctx, cfg, tmpls, routeNotifiers(n), sg, paths, w); err != nil {
+			return err
+		}
+	}
+
+	Debugf(ctx, "setup check successful")
+	return nil
+}
+
+// expandBuildPatterns returns the paths of the sample Builds that match the given comma-separated paths or globs.
+func expandBuildPatterns(patterns string) ([]string, error) {
+	var paths []string
+	for _, p := range strings.Split(patterns, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		matches, err := filepath.Glob(p)
+		if err != nil {
+			return nil, fmt.Errorf("got invalid sample Build pattern %q: %w", p, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("expected sample Build pattern %q to match at least one file", p)
+		}
+		paths = append(paths, matches...)
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("expected sample Build patterns %q to be non-empty", patterns)
+	}
+	return paths, nil
+}
+
+// routeNotifiers returns the notifier of every route of a Notifier returned by setUpNotifier. Since they do not
+// apply filters, the paramsNotifiers of the routes are unwrapped.
+func routeNotifiers(n Notifier) []Notifier {
+	routes := []Notifier{n}
+	if mn, ok := n.(*multiNotifier); ok {
+		routes = append([]Notifier(nil), mn.routes...)
+	}
+	for i, r := range routes {
+		if pn, ok := r.(*paramsNotifier); ok {
+			routes[i] = pn.notifier
+		}
+	}
+	return routes
+}
+
+// checkSamples checks the filter, params, and rendering of every route against every sample Build at the given
+// paths and reports the results to w. A filter that does not match a sample is not a failure, but a filter that fails
+// to evaluate, a param that fails to resolve, or a template that fails to render is.
+func checkSamples(ctx context.Context, cfg *Config, tmpls []string, routes []Notifier, sg SecretGetter, paths []string, w io.Writer) error {
+	var failures []error
+	fail := func(format string, args ...interface{}) {
+		err := fmt.Errorf(format, args...)
+		failures = append(failures, err)
+		fmt.Fprintf(w, "    FAIL: %v\n", err)
+	}
+
+	for _, path := range paths {
+		fmt.Fprintf(w, "%s:\n", path)
+		build, err := readBuild(path)
+		if err != nil {
+			failures = append(failures, err)
+			fmt.Fprintf(w, "  FAIL: %v\n", err)
+			continue
+		}
+		if build.GetId() == "" {
+			build.Id = renderBuildID
+		}
+
+		for i, route := range cfg.Spec.Routes() {
+			fmt.Fprintf(w, "  notification route %d:\n", i)
+
+			br, err := newResolver(routeConfig(cfg, route))
+			if err != nil {
+				fail("%s: route %d: failed to make params resolver: %v", path, i, err)
+				continue
+			}
+			// The filter can refer to the params, so they are resolved first but reported after it.
+			params, paramsErr := br.Resolve(ctx, sg, build)
+
+			filter, err := MakeCELPredicate(route.Filter)
+			if err != nil {
+				fail("%s: route %d: failed to make filter: %v", path, i, err)
+				continue
+			}
+			switch match, err := filter.eval(withFilterParams(ctx, params), build); {
+			case err != nil:
+				fail("%s: route %d: failed to evaluate filter: %v", path, i, err)
+			case match:
+				fmt.Fprintf(w, "    filter: matched\n")
+			default:
+				fmt.Fprintf(w, "    filter: did not match\n")
+			}
+
+			if paramsErr != nil {
+				fail("%s: route %d: failed to resolve params: %v", path, i, paramsErr)
+			} else {
+				names := make([]string, 0, len(params))
+				for name := range params {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+				for _, name := range names {
+					fmt.Fprintf(w, "    param %s: %q\n", name, params[name])
+				}
+			}
+
+			// Notifiers may modify the Build, so every route gets its own copy.
+			payload, err := renderSample(ctx, routes[i], tmpls[i], params, proto.Clone(build).(*cbpb.Build))
+			switch {
+			case err != nil:
+				fail("%s: route %d: failed to render: %v", path, i, err)
+			case payload == nil:
+				fmt.Fprintf(w, "    render: skipped (no template)\n")
+			default:
+				fmt.Fprintf(w, "    render: ok (%d bytes)\n", len(payload))
+			}
+		}
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("setup check failed %d check(s) against %d sample Build(s): %w", len(failures), len(paths), errors.Join(failures...))
+	}
+	return nil
+}
+
+// renderSample renders the payload of a route for the given Build. Notifiers that implement Renderer render their
+// exact payload; for others, the route's template (if any) is executed with the Build and params. A nil payload
+// means that there was nothing to render.
+func renderSample(ctx context.Context, n Notifier, tmpl string, params map[string]string, build *cbpb.Build) ([]byte, error) {
+	if r, ok := n.(Renderer); ok {
+		payload, err := r.Render(ctx, build)
+		if err == nil && payload == nil {
+			payload = []byte{}
+		}
+		return payload, err
+	}
+	if tmpl == "" {
+		return nil, nil
+	}
+
+	t, err := template.New("").Funcs(TemplateFuncs()).Parse(tmpl)
+	if err != nil {
+		return nil, err
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, NewTemplateView(ctx, build, params)); err != nil {
+		return nil, err
+	}
+	return []byte(b.String()), nil
+}
@@ -0,0 +1,148 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifiers
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRunSetupCheck(t *testing.T) {
+	const cfgFmt = `
+apiVersion: cloud-build-notifiers/v1
+kind: SetupCheckNotifier
+spec:
+  notification:
+    filter: build.status == Build.Status.SUCCESS
+    delivery:
+      greeting: Yay
+    params:
+      branch: $(build.substitutions.BRANCH_NAME)
+    template:
+      type: golang
+      content: '%s'
+`
+	dir := t.TempDir()
+	writeFile(t, dir, "success.yaml", "id: some-build-id\nstatus: SUCCESS\nsubstitutions:\n  BRANCH_NAME: main\n")
+	writeFile(t, dir, "failure.yaml", "id: other-build-id\nstatus: FAILURE\nsubstitutions:\n  BRANCH_NAME: dev\n")
+	writeFile(t, dir, "manual.json", `{"id": "manual-build-id", "status": "SUCCESS"}`)
+
+	for _, tc := range []struct {
+		name     string
+		notifier Notifier
+		tmpl     string
+		builds   string
+		want     string
+		wantErr  bool
+	}{{
+		name:     "no sample builds",
+		notifier: new(renderingNotifier),
+		tmpl:     "{{.Build.Id}}",
+	}, {
+		name:     "renderer",
+		notifier: new(renderingNotifier),
+		tmpl:     "{{.Params.greeting}} {{.Build.Id}}",
+		builds:   filepath.Join(dir, "*.yaml"),
+		want: "failure.yaml:\n  notification route 0:\n    filter: did not match\n    param branch: \"dev\"\n    render: ok (18 bytes)\n" +
+			"success.yaml:\n  notification route 0:\n    filter: matched\n    param branch: \"main\"\n    render: ok (17 bytes)\n",
+	}, {
+		name:     "template fallback",
+		notifier: &setUpCountingNotifier{setUps: new(int)},
+		tmpl:     "{{.Params.branch | upper}}",
+		builds:   filepath.Join(dir, "success.yaml"),
+		want:     "success.yaml:\n  notification route 0:\n    filter: matched\n    param branch: \"main\"\n    render: ok (4 bytes)\n",
+	}, {
+		name:     "missing param",
+		notifier: new(renderingNotifier),
+		tmpl:     "{{.Build.Id}}",
+		builds:   filepath.Join(dir, "success.yaml") + "," + filepath.Join(dir, "manual.json"),
+		want: "success.yaml:\n  notification route 0:\n    filter: matched\n    param branch: \"main\"\n    render: ok (13 bytes)\n" +
+			"manual.json:\n  notification route 0:\n    filter: matched\n    FAIL: manual.json: route 0: failed to resolve params\n    render: ok (15 bytes)\n",
+		wantErr: true,
+	}, {
+		name:     "bad template",
+		notifier: new(renderingNotifier),
+		tmpl:     "{{.Build.NoSuchField}}",
+		builds:   filepath.Join(dir, "success.yaml"),
+		want:     "success.yaml:\n  notification route 0:\n    filter: matched\n    param branch: \"main\"\n    FAIL: success.yaml: route 0: failed to render\n",
+		wantErr:  true,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			var out strings.Builder
+			err := runSetupCheck(context.Background(), tc.notifier, strings.NewReader(fmt.Sprintf(cfgFmt, tc.tmpl)), &configSource{}, tc.builds, &out)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("runSetupCheck returned error %v, want error: %v", err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, trimReport(dir, out.String())); diff != "" {
+				t.Errorf("unexpected report diff: (want- got+)\n%s", diff)
+			}
+		})
+	}
+}
+
+// trimReport strips the temporary directory from the paths in a setup check report, and the details from its
+// failures, which come from other packages.
+func trimReport(dir, report string) string {
+	lines := strings.Split(strings.ReplaceAll(report, dir+string(filepath.Separator), ""), "\n")
+	for i, l := range lines {
+		// Failures look like `FAIL: <path>: route <n>: <check>: <details>`.
+		if k := strings.Index(l, "route "); strings.Contains(l, "FAIL: ") && k >= 0 {
+			if parts := strings.SplitN(l[k:], ": ", 3); len(parts) == 3 {
+				lines[i] = l[:k] + parts[0] + ": " + parts[1]
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestExpandBuildPatterns(t *testing.T) {
+	dir := t.TempDir()
+	a := writeFile(t, dir, "a.yaml", "id: a")
+	b := writeFile(t, dir, "b.yaml", "id: b")
+
+	got, err := expandBuildPatterns(filepath.Join(dir, "*.yaml") + ", " + a)
+	if err != nil {
+		t.Fatalf("expandBuildPatterns failed: %v", err)
+	}
+	if diff := cmp.Diff([]string{a, b, a}, got); diff != "" {
+		t.Errorf("unexpected paths diff: (want- got+)\n%s", diff)
+	}
+
+	for _, p := range []string{filepath.Join(dir, "*.json"), " , ", "["} {
+		if _, err := expandBuildPatterns(p); err == nil {
+			t.Errorf("expandBuildPatterns(%q) succeeded, want error", p)
+		}
+	}
+}
+
+func TestRunSetupCheckInvalidFilter(t *testing.T) {
+	cfg := `
+apiVersion: cloud-build-notifiers/v1
+kind: SetupCheckNotifier
+spec:
+  notifications:
+  - filter: hasTag(build, "nightly")
+  - filter: hasTag(build)
+`
+	err := runSetupCheck(context.Background(), &setUpCountingNotifier{setUps: new(int)}, strings.NewReader(cfg), &configSource{}, "", new(strings.Builder))
+	if err == nil || !strings.Contains(err.Error(), "notification route 1") {
+		t.Errorf("runSetupCheck returned error %v, want an invalid filter error for notification route 1", err)
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifiers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// defaultDrainTimeout matches the 10 seconds that Cloud Run waits between SIGTERM and SIGKILL.
+const defaultDrainTimeout = 10 * time.Second
+
+// drainTimeoutFromEnv returns DRAIN_TIMEOUT, which bounds how long shutdown waits for in-flight notifications.
+func drainTimeoutFromEnv() (time.Duration, error) {
+	dt, ok := GetEnv("DRAIN_TIMEOUT")
+	if !ok {
+		return defaultDrainTimeout, nil
+	}
+	d, err := time.ParseDuration(dt)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("expected DRAIN_TIMEOUT %q to be a positive duration", dt)
+	}
+	return d, nil
+}
+
+// drainer tracks in-flight work so that shutdown can wait for it. Once draining starts, no new work is accepted.
+type drainer struct {
+	mtx      sync.Mutex
+	draining bool
+	wg       sync.WaitGroup
+	// flushes send the notifications that were held back for later, once the in-flight work is done.
+	flushes []func(context.Context)
+}
+
+// acquire returns true iff the caller may start a unit of work, in which case it must call release when done.
+func (d *drainer) acquire() bool {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	if d.draining {
+		return false
+	}
+	d.wg.Add(1)
+	return true
+}
+
+func (d *drainer) release() {
+	d.wg.Done()
+}
+
+// onDrain registers a function that drain calls to send the notifications that were held back for later. It gets
+// the context of drain, so it must give up once that is done.
+func (d *drainer) onDrain(flush func(context.Context)) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	d.flushes = append(d.flushes, flush)
+}
+
+// isDraining returns true iff drain was called.
+func (d *drainer) isDraining() bool {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	return d.draining
+}
+
+// drain stops accepting work and waits for the in-flight work to be released or for the context to be done. Then it
+// calls the functions registered with onDrain, even if the context is done, so that they can report what they lose.
+func (d *drainer) drain(ctx context.Context) error {
+	d.mtx.Lock()
+	d.draining = true
+	flushes := d.flushes
+	d.mtx.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	for _, flush := range flushes {
+		flush(ctx)
+	}
+	return err
+}
+
+// detachedUntil returns a context that keeps the values (and the log fields) of ctx, but is only canceled once done
+// is, so that the work it was held back for can still be sent while draining.
+func detachedUntil(ctx, done context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(done, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// serve serves HTTP requests on the given listener until the server fails, an error is sent on errc, or the context
+// is done. In the last case, the server stops accepting connections and serve waits (for at most the drain timeout)
+// for the in-flight requests and the work tracked by the drainer to finish.
+func serve(ctx context.Context, srv *http.Server, ln net.Listener, errc chan error, d *drainer, drainTimeout time.Duration) error {
+	go func() {
+		if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+			errc <- err
+		}
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	Infof(context.Background(), "shutting down, waiting up to %s for in-flight notifications", drainTimeout)
+	dctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+	// Draining runs even if shutting down the server timed out, so that the held back notifications are still sent
+	// (or reported as lost).
+	var errs []error
+	if err := srv.Shutdown(dctx); err != nil {
+		errs = append(errs, fmt.Errorf("failed to shut down the HTTP server: %w", err))
+	}
+	if err := d.drain(dctx); err != nil {
+		errs = append(errs, fmt.Errorf("failed to drain in-flight notifications: %w", err))
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	Infof(context.Background(), "shut down gracefully")
+	return nil
+}
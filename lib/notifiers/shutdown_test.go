@@ -0,0 +1,178 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifiers
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	cbpb "cloud.google.com/go/cloudbuild/apiv1/v2/cloudbuildpb"
+)
+
+// blockingNotifier blocks in SendNotification until it is unblocked.
+type blockingNotifier struct {
+	started chan struct{}
+	unblock chan struct{}
+}
+
+func newBlockingNotifier() *blockingNotifier {
+	return &blockingNotifier{started: make(chan struct{}, 1), unblock: make(chan struct{})}
+}
+
+func (b *blockingNotifier) SetUp(_ context.Context, _ *Config, _ string, _ SecretGetter, _ BindingResolver) error {
+	return nil
+}
+
+func (b *blockingNotifier) SendNotification(_ context.Context, _ *cbpb.Build) error {
+	b.started <- struct{}{}
+	<-b.unblock
+	return nil
+}
+
+func pushBody(id string) string {
+	return fmt.Sprintf(`{"message": {"data": %q, "id": %q}, "subscription": "projects/p/subscriptions/s"}`,
+		base64.StdEncoding.EncodeToString([]byte(`{"id": "build-id", "status": "SUCCESS"}`)), id)
+}
+
+func TestDrainer(t *testing.T) {
+	d := new(drainer)
+	if !d.acquire() {
+		t.Fatal("acquire() = false before draining, want true")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := d.drain(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("drain() with in-flight work = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if d.acquire() {
+		t.Error("acquire() = true while draining, want false")
+	}
+
+	d.release()
+	if err := d.drain(context.Background()); err != nil {
+		t.Errorf("drain() without in-flight work = %v, want nil", err)
+	}
+}
+
+func TestReceiverShuttingDown(t *testing.T) {
+	d := new(drainer)
+	if err := d.drain(context.Background()); err != nil {
+		t.Fatalf("drain failed: %v", err)
+	}
+
+	n := new(eventRecordingNotifier)
+	req := httptest.NewRequest(http.MethodPost, "http://notifer.example.com/", strings.NewReader(pushBody("message-id")))
+	w := httptest.NewRecorder()
+	newReceiver(n, &receiverParams{drainer: d})(w, req)
+
+	if s := w.Result().StatusCode; s != http.StatusServiceUnavailable {
+		t.Errorf("result.StatusCode = %d, expected %d", s, http.StatusServiceUnavailable)
+	}
+	if n.buildID != "" {
+		t.Error("expected no notification to be sent while shutting down")
+	}
+}
+
+func TestServeDrainsInFlightNotifications(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	n := newBlockingNotifier()
+	d := new(drainer)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", newReceiver(n, &receiverParams{drainer: d}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- serve(ctx, &http.Server{Handler: mux}, ln, make(chan error, 1), d, time.Minute)
+	}()
+
+	url := "http://" + ln.Addr().String()
+	posted := make(chan int, 1)
+	go func() {
+		resp, err := http.Post(url, "application/json", strings.NewReader(pushBody("message-id")))
+		if err != nil {
+			t.Errorf("failed to post message: %v", err)
+			posted <- 0
+			return
+		}
+		resp.Body.Close()
+		posted <- resp.StatusCode
+	}()
+
+	<-n.started
+	cancel()
+	select {
+	case err := <-served:
+		t.Fatalf("serve returned %v with a notification in flight", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(n.unblock)
+	if s := <-posted; s != http.StatusOK {
+		t.Errorf("in-flight request got status %d, expected %d", s, http.StatusOK)
+	}
+	if err := <-served; err != nil {
+		t.Errorf("serve returned %v, expected nil", err)
+	}
+	if _, err := http.Post(url, "application/json", strings.NewReader(pushBody("other-id"))); err == nil {
+		t.Error("expected requests to fail after shutting down")
+	}
+}
+
+func TestServeDrainsAfterShutdownTimeout(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	// The request is not tracked by the drainer, so only shutting down the server times out.
+	started, unblock := make(chan struct{}), make(chan struct{})
+	defer close(unblock)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-unblock
+	})
+	d := new(drainer)
+	flushed := false
+	d.onDrain(func(context.Context) { flushed = true })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- serve(ctx, &http.Server{Handler: mux}, ln, make(chan error, 1), d, 50*time.Millisecond)
+	}()
+	go http.Get("http://" + ln.Addr().String())
+
+	<-started
+	cancel()
+	if err := <-served; !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("serve returned %v, want %v", err, context.DeadlineExceeded)
+	}
+	if !flushed {
+		t.Error("expected the held back notifications to be flushed after shutting down the server timed out")
+	}
+}
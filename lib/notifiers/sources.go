@@ -0,0 +1,170 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifiers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/storage"
+)
+
+// configFetchTimeout bounds how long fetching a config or template from an `https://` URL may take, so that a hung
+// server can neither block startup nor hold up a reload (and the deliveries waiting for it) forever.
+const configFetchTimeout = 30 * time.Second
+
+// configSource reads configs and templates from `gs://` URIs (through its gcsReaderFactory), `https://` URLs,
+// `file://` URIs, and plain local paths. Plain `http://` URLs are rejected, since a config fetched in cleartext could
+// be tampered with in transit.
+type configSource struct {
+	grf gcsReaderFactory
+	// client is used for `https://` URLs. If nil, a client with a timeout of configFetchTimeout is used.
+	client *http.Client
+}
+
+func (s *configSource) httpClient() *http.Client {
+	if s.client == nil {
+		return &http.Client{Timeout: configFetchTimeout}
+	}
+	return s.client
+}
+
+// getConfig fetches the YAML Config file from the given URI and returns the parsed Config.
+func (s *configSource) getConfig(ctx context.Context, uri string) (*Config, error) {
+	if strings.HasPrefix(uri, "gs://") {
+		return getGCSConfig(ctx, s.grf, uri)
+	}
+
+	r, err := s.open(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	cfg, err := decodeConfig(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse configuration from YAML at %q: %w", uri, err)
+	}
+	return cfg, nil
+}
+
+// getTemplate fetches the Template file from the given URI.
+func (s *configSource) getTemplate(ctx context.Context, uri string) (string, error) {
+	if strings.HasPrefix(uri, "gs://") {
+		return getGCSTemplate(ctx, s.grf, uri)
+	}
+
+	r, err := s.open(ctx, uri)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	tmpl, err := decodeTemplate(r)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse template at %q: %w", uri, err)
+	}
+	return tmpl, nil
+}
+
+// open returns a reader for the given non-GCS URI.
+func (s *configSource) open(ctx context.Context, uri string) (io.ReadCloser, error) {
+	switch {
+	case strings.HasPrefix(uri, "https://"):
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create a new HTTP request for %q: %w", uri, err)
+		}
+		resp, err := s.httpClient().Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to make HTTP request for %q: %w", uri, err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("got a non-OK response status %q (%d) from %q", resp.Status, resp.StatusCode, uri)
+		}
+		return resp.Body, nil
+	case strings.HasPrefix(uri, "http://"):
+		return nil, fmt.Errorf("expected %q to use `https://` rather than `http://`", uri)
+	case strings.HasPrefix(uri, "file://"):
+		return os.Open(strings.TrimPrefix(uri, "file://"))
+	case strings.Contains(uri, "://"):
+		return nil, fmt.Errorf("expected %q to be a local path or to start with one of `gs://`, `https://`, or `file://`", uri)
+	default:
+		return os.Open(uri)
+	}
+}
+
+// resolveURI resolves the given (template) reference relative to the given base (config) URI or path.
+// Absolute references are returned as-is, as are all references when the base is empty (e.g. for inline configs).
+func resolveURI(base, ref string) string {
+	if base == "" || ref == "" || strings.Contains(ref, "://") || filepath.IsAbs(ref) {
+		return ref
+	}
+
+	if !strings.Contains(base, "://") {
+		return filepath.Join(filepath.Dir(base), ref)
+	}
+
+	bu, err := url.Parse(base)
+	if err != nil {
+		return ref
+	}
+	ru, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return bu.ResolveReference(ru).String()
+}
+
+// lazyGCSClient creates its GCS client on first use, so that notifiers that never touch GCS do not need GCP
+// credentials.
+type lazyGCSClient struct {
+	mtx    sync.Mutex
+	client *storage.Client
+}
+
+func (l *lazyGCSClient) get() (*storage.Client, error) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if l.client == nil {
+		c, err := storage.NewClient(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create new GCS client: %w", err)
+		}
+		l.client = c
+	}
+	return l.client, nil
+}
+
+// Close closes the GCS client if it was ever created.
+func (l *lazyGCSClient) Close() error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if l.client == nil {
+		return nil
+	}
+	return l.client.Close()
+}
@@ -0,0 +1,228 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifiers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const sourcesConfigYAML = `
+apiVersion: cloud-build-notifiers/v1
+kind: TestNotifier
+spec:
+  notification:
+    filter: build.status == Build.Status.SUCCESS
+    template:
+      type: golang
+      uri: %s
+`
+
+func TestResolveURI(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		base string
+		ref  string
+		want string
+	}{{
+		name: "empty base",
+		ref:  "template.json",
+		want: "template.json",
+	}, {
+		name: "absolute ref",
+		base: "gs://bucket/config.yaml",
+		ref:  "https://example.com/template.json",
+		want: "https://example.com/template.json",
+	}, {
+		name: "absolute path ref",
+		base: "/etc/notifier/config.yaml",
+		ref:  "/tmp/template.json",
+		want: "/tmp/template.json",
+	}, {
+		name: "local path base",
+		base: "/etc/notifier/config.yaml",
+		ref:  "templates/template.json",
+		want: "/etc/notifier/templates/template.json",
+	}, {
+		name: "gcs base",
+		base: "gs://bucket/configs/config.yaml",
+		ref:  "template.json",
+		want: "gs://bucket/configs/template.json",
+	}, {
+		name: "https base",
+		base: "https://example.com/configs/config.yaml",
+		ref:  "../templates/template.json",
+		want: "https://example.com/templates/template.json",
+	}, {
+		name: "file base",
+		base: "file:///etc/notifier/config.yaml",
+		ref:  "template.json",
+		want: "file:///etc/notifier/template.json",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := resolveURI(tc.base, tc.ref); got != tc.want {
+				t.Errorf("resolveURI(%q, %q) = %q, want %q", tc.base, tc.ref, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestConfigSourceLocalFiles(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(cfgPath, []byte(fmt.Sprintf(sourcesConfigYAML, "template.json")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "template.json"), []byte("{{.Build.Id}}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{cfgPath, "file://" + cfgPath} {
+		t.Run(path, func(t *testing.T) {
+			cl := &configLoader{notifier: new(setUpCountingNotifier), path: path, src: new(configSource)}
+			cfg, tmpls, _, err := cl.fetch(ctx)
+			if err != nil {
+				t.Fatalf("fetch failed: %v", err)
+			}
+			if diff := cmp.Diff("build.status == Build.Status.SUCCESS", cfg.Spec.Notification.Filter); diff != "" {
+				t.Errorf("unexpected filter diff: (want- got+)\n%s", diff)
+			}
+			if diff := cmp.Diff([]string{"{{.Build.Id}}"}, tmpls); diff != "" {
+				t.Errorf("unexpected templates diff: (want- got+)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestConfigSourceHTTPS(t *testing.T) {
+	ctx := context.Background()
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/configs/config.yaml":
+			fmt.Fprintf(w, sourcesConfigYAML, "../templates/template.json")
+		case "/templates/template.json":
+			fmt.Fprint(w, "{{.Build.Id}}")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	src := &configSource{client: srv.Client()}
+	cl := &configLoader{notifier: new(setUpCountingNotifier), path: srv.URL + "/configs/config.yaml", src: src}
+	_, tmpls, _, err := cl.fetch(ctx)
+	if err != nil {
+		t.Fatalf("fetch failed: %v", err)
+	}
+	if diff := cmp.Diff([]string{"{{.Build.Id}}"}, tmpls); diff != "" {
+		t.Errorf("unexpected templates diff: (want- got+)\n%s", diff)
+	}
+
+	if _, err := src.getConfig(ctx, srv.URL+"/missing.yaml"); err == nil {
+		t.Error("getConfig for a missing URL unexpectedly succeeded")
+	}
+}
+
+func TestConfigSourceRejectsHTTP(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprintf(w, sourcesConfigYAML, "template.json")
+	}))
+	defer srv.Close()
+
+	src := &configSource{client: srv.Client()}
+	if _, err := src.getConfig(context.Background(), srv.URL+"/config.yaml"); err == nil {
+		t.Error("getConfig for an http:// URL unexpectedly succeeded")
+	}
+	if _, err := src.getTemplate(context.Background(), srv.URL+"/template.json"); err == nil {
+		t.Error("getTemplate for an http:// URL unexpectedly succeeded")
+	}
+	if requests != 0 {
+		t.Errorf("got %d requests to the http:// server, want 0", requests)
+	}
+}
+
+func TestConfigSourceTimeout(t *testing.T) {
+	if c := new(configSource).httpClient(); c.Timeout != configFetchTimeout {
+		t.Errorf("got default client timeout %v, want %v", c.Timeout, configFetchTimeout)
+	}
+
+	unblock := make(chan struct{})
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-unblock
+	}))
+	defer srv.Close()
+	defer close(unblock)
+
+	client := srv.Client()
+	client.Timeout = 50 * time.Millisecond
+	src := &configSource{client: client}
+	if _, err := src.getConfig(context.Background(), srv.URL+"/config.yaml"); err == nil {
+		t.Error("getConfig for a hung server unexpectedly succeeded")
+	}
+}
+
+func TestConfigSourceErrors(t *testing.T) {
+	ctx := context.Background()
+	src := new(configSource)
+	for _, uri := range []string{
+		"s3://bucket/config.yaml",
+		filepath.Join(t.TempDir(), "missing.yaml"),
+	} {
+		t.Run(uri, func(t *testing.T) {
+			if _, err := src.getConfig(ctx, uri); err == nil {
+				t.Errorf("getConfig(%q) unexpectedly succeeded", uri)
+			}
+		})
+	}
+}
+
+func TestConfigLoaderInline(t *testing.T) {
+	ctx := context.Background()
+	grf := &fakeGCSReaderFactory{
+		data: map[string]string{"gs://bucket/template.json": "{{.Build.Id}}"},
+	}
+	var setUps int
+	cl := &configLoader{
+		notifier: &setUpCountingNotifier{setUps: &setUps},
+		inline:   fmt.Sprintf(sourcesConfigYAML, "gs://bucket/template.json"),
+		src:      &configSource{grf: grf},
+		sg:       newCachingSecretGetter(new(setupCheckSecretGetter), 0),
+	}
+
+	ld, err := cl.load(ctx)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	n := ld.notifier.(*setUpCountingNotifier)
+	if diff := cmp.Diff("{{.Build.Id}}", n.tmpl); diff != "" {
+		t.Errorf("unexpected template diff: (want- got+)\n%s", diff)
+	}
+
+	cl.inline = "not: [valid"
+	if _, err := cl.load(ctx); err == nil {
+		t.Error("load with invalid inline YAML unexpectedly succeeded")
+	}
+}
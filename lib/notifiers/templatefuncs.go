@@ -0,0 +1,220 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifiers
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	cbpb "cloud.google.com/go/cloudbuild/apiv1/v2/cloudbuildpb"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// TemplateFuncs returns the functions that are available to every notifier's templates. The map can be passed to
+// the Funcs method of both text/template and html/template templates.
+//
+// Functions whose last argument is the value that they operate on can be used in pipelines, e.g.
+// `{{.Build.Id | truncate 8}}` or `{{.Params.owner | default "nobody"}}`.
+func TemplateFuncs() map[string]interface{} {
+	return map[string]interface{}{
+		// Strings.
+		"replace":    func(s, old, new string) string { return strings.ReplaceAll(s, old, new) },
+		"lower":      strings.ToLower,
+		"upper":      strings.ToUpper,
+		"trim":       strings.TrimSpace,
+		"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+		"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+		"contains":   func(substr, s string) bool { return strings.Contains(s, substr) },
+		"hasPrefix":  func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+		"hasSuffix":  func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+		"split":      func(sep, s string) []string { return strings.Split(s, sep) },
+		"join":       func(sep string, elems []string) string { return strings.Join(elems, sep) },
+		"truncate":   truncate,
+		"default":    defaultValue,
+
+		// JSON.
+		"toJson": toJSON,
+
+		// Times and durations.
+		"formatTime":     formatTime,
+		"duration":       duration,
+		"formatDuration": formatDuration,
+
+		// Build statuses.
+		"statusEmoji": StatusEmoji,
+		"statusColor": StatusColor,
+	}
+}
+
+// truncate shortens s to at most n runes, ending it with an ellipsis if anything was cut.
+func truncate(n int, s string) string {
+	r := []rune(s)
+	if n < 0 || len(r) <= n {
+		return s
+	}
+	if n == 0 {
+		return ""
+	}
+	return string(r[:n-1]) + "…"
+}
+
+// defaultValue returns v, or def if v is empty (nil or the zero value of its type, including empty strings, slices,
+// and maps).
+func defaultValue(def, v interface{}) interface{} {
+	if v == nil {
+		return def
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array, reflect.String:
+		if rv.Len() == 0 {
+			return def
+		}
+	default:
+		if rv.IsZero() {
+			return def
+		}
+	}
+	return v
+}
+
+// toJSON returns the JSON encoding of v, e.g. to embed a string in a JSON template with its quotes and escapes.
+// Protocol buffer messages (e.g. the Build) are encoded with their canonical JSON mapping.
+func toJSON(v interface{}) (string, error) {
+	if bv, ok := v.(*BuildView); ok {
+		v = bv.Build
+	}
+	var b []byte
+	var err error
+	if m, ok := v.(proto.Message); ok {
+		b, err = protojson.Marshal(m)
+	} else {
+		b, err = json.Marshal(v)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to encode %T as JSON: %w", v, err)
+	}
+	return string(b), nil
+}
+
+// asTime converts a time.Time or a Timestamp (e.g. the Build's `StartTime`) to a time.Time.
+func asTime(v interface{}) (time.Time, error) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, nil
+	case *timestamppb.Timestamp:
+		if t == nil {
+			return time.Time{}, nil
+		}
+		return t.AsTime(), nil
+	default:
+		return time.Time{}, fmt.Errorf("expected a time or a Timestamp, got %T", v)
+	}
+}
+
+// formatTime formats a time.Time or a Timestamp with the given Go layout (e.g. `2006-01-02 15:04 MST`) or the name
+// of one of the layouts of the time package (e.g. `RFC3339` or `Kitchen`). Zero times are formatted as "".
+func formatTime(layout string, v interface{}) (string, error) {
+	t, err := asTime(v)
+	if err != nil || t.IsZero() {
+		return "", err
+	}
+	if l, ok := timeLayouts[layout]; ok {
+		layout = l
+	}
+	return t.Format(layout), nil
+}
+
+var timeLayouts = map[string]string{
+	"ANSIC":       time.ANSIC,
+	"UnixDate":    time.UnixDate,
+	"RFC822":      time.RFC822,
+	"RFC1123":     time.RFC1123,
+	"RFC3339":     time.RFC3339,
+	"RFC3339Nano": time.RFC3339Nano,
+	"Kitchen":     time.Kitchen,
+	"DateTime":    time.DateTime,
+	"DateOnly":    time.DateOnly,
+	"TimeOnly":    time.TimeOnly,
+}
+
+// duration returns the time between start and end, each a time.Time or a Timestamp, e.g.
+// `{{duration .Build.StartTime .Build.FinishTime}}`. It is 0 if either is unset.
+func duration(start, end interface{}) (time.Duration, error) {
+	s, err := asTime(start)
+	if err != nil {
+		return 0, err
+	}
+	e, err := asTime(end)
+	if err != nil {
+		return 0, err
+	}
+	if s.IsZero() || e.IsZero() {
+		return 0, nil
+	}
+	return e.Sub(s), nil
+}
+
+// formatDuration formats a time.Duration or a Duration (e.g. the Build's `Timeout`) rounded to the second, e.g.
+// "1h2m3s".
+func formatDuration(v interface{}) (string, error) {
+	var d time.Duration
+	switch t := v.(type) {
+	case time.Duration:
+		d = t
+	case *durationpb.Duration:
+		d = t.AsDuration()
+	default:
+		return "", fmt.Errorf("expected a duration, got %T", v)
+	}
+	return d.Round(time.Second).String(), nil
+}
+
+// StatusEmoji returns an emoji for the given Build status.
+func StatusEmoji(s cbpb.Build_Status) string {
+	switch s {
+	case cbpb.Build_SUCCESS:
+		return "✅"
+	case cbpb.Build_FAILURE, cbpb.Build_INTERNAL_ERROR:
+		return "❌"
+	case cbpb.Build_TIMEOUT, cbpb.Build_EXPIRED:
+		return "⏰"
+	case cbpb.Build_CANCELLED:
+		return "🚫"
+	case cbpb.Build_QUEUED, cbpb.Build_PENDING, cbpb.Build_WORKING:
+		return "⏳"
+	default:
+		return "❔"
+	}
+}
+
+// StatusColor returns a hex color (e.g. for a Slack attachment) for the given Build status: green for passing, red
+// for failing, and amber for every other status.
+func StatusColor(s cbpb.Build_Status) string {
+	switch s {
+	case cbpb.Build_SUCCESS:
+		return "#22bb33"
+	case cbpb.Build_FAILURE, cbpb.Build_INTERNAL_ERROR, cbpb.Build_TIMEOUT:
+		return "#bb2124"
+	default:
+		return "#f0ad4e"
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifiers
+
+import (
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+
+	cbpb "cloud.google.com/go/cloudbuild/apiv1/v2/cloudbuildpb"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestTemplateFuncs(t *testing.T) {
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	view := &TemplateView{
+		Build: &BuildView{Build: &cbpb.Build{
+			Id:         "0123456789abcdef",
+			Status:     cbpb.Build_FAILURE,
+			StartTime:  timestamppb.New(start),
+			FinishTime: timestamppb.New(start.Add(90*time.Minute + 1500*time.Millisecond)),
+			Timeout:    durationpb.New(10 * time.Minute),
+			LogUrl:     `https://example.com/"logs"`,
+		}},
+		Params: map[string]string{"owner": "", "team": "Build Cop"},
+	}
+
+	for _, tc := range []struct {
+		tmpl string
+		want string
+	}{
+		{tmpl: `{{replace .Build.LogUrl "\"" "'"}}`, want: `https://example.com/'logs'`},
+		{tmpl: `{{.Params.team | lower}} {{.Params.team | upper}}`, want: "build cop BUILD COP"},
+		{tmpl: `{{.Params.team | trimPrefix "Build "}}`, want: "Cop"},
+		{tmpl: `{{if .Params.team | hasPrefix "Build"}}yes{{end}}`, want: "yes"},
+		{tmpl: `{{split " " .Params.team | join "-"}}`, want: "Build-Cop"},
+		{tmpl: `{{.Build.Id | truncate 8}} {{.Build.Id | truncate 16}}`, want: "0123456… 0123456789abcdef"},
+		{tmpl: `{{.Params.owner | default "nobody"}} {{.Params.team | default "nobody"}}`, want: "nobody Build Cop"},
+		{tmpl: `{{.Params.missing | default "nobody"}}`, want: "nobody"},
+		{tmpl: `{"url": {{toJson .Build.LogUrl}}}`, want: `{"url": "https://example.com/\"logs\""}`},
+		{tmpl: `{{toJson .Params}}`, want: `{"owner":"","team":"Build Cop"}`},
+		{tmpl: `{{formatTime "RFC3339" .Build.StartTime}}`, want: "2026-01-02T03:04:05Z"},
+		{tmpl: `{{formatTime "15:04" .Build.StartTime}}|{{formatTime "15:04" .Build.CreateTime}}`, want: "03:04|"},
+		{tmpl: `{{duration .Build.StartTime .Build.FinishTime | formatDuration}}`, want: "1h30m2s"},
+		{tmpl: `{{formatDuration .Build.Timeout}}`, want: "10m0s"},
+		{tmpl: `{{statusEmoji .Build.Status}} {{statusColor .Build.Status}}`, want: "❌ #bb2124"},
+	} {
+		t.Run(tc.tmpl, func(t *testing.T) {
+			tmpl, err := template.New("").Funcs(TemplateFuncs()).Parse(tc.tmpl)
+			if err != nil {
+				t.Fatalf("failed to parse template: %v", err)
+			}
+			var b strings.Builder
+			if err := tmpl.Execute(&b, view); err != nil {
+				t.Fatalf("failed to execute template: %v", err)
+			}
+			if got := b.String(); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestValidateTemplateFuncs(t *testing.T) {
+	// The validator knows the same functions as the notifiers.
+	if err := validateTemplate(`{{.Build.Id | truncate 8}} {{statusEmoji .Build.Status}} {{toJson .Params}}`); err != nil {
+		t.Errorf("validateTemplate failed: %v", err)
+	}
+	if err := validateTemplate(`{{nope .Build.Id}}`); err == nil {
+		t.Error("validateTemplate succeeded with an unknown function, want error")
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifiers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/GoogleCloudPlatform/cloud-build-notifiers/lib/notifiers"
+
+// tracePropagator extracts the incoming trace context (if any) of received messages, so that their spans continue the
+// publisher's trace.
+var tracePropagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+// setUpTracing installs the global TracerProvider according to OTEL_TRACES_EXPORTER, which is one of:
+// - `otlp`: export to an OTLP collector, which is configured by the standard OTEL_EXPORTER_OTLP_* variables.
+// - `stdout`: write the spans to stdout.
+// - `none` or unset: do not export spans.
+// The returned function flushes and stops the exporter.
+func setUpTracing(ctx context.Context) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(tracePropagator)
+
+	var exp sdktrace.SpanExporter
+	var err error
+	switch e, _ := GetEnv("OTEL_TRACES_EXPORTER"); e {
+	case "", "none":
+		return func(context.Context) error { return nil }, nil
+	case "otlp":
+		// The protocol defaults to `http/protobuf` as per the OpenTelemetry specification.
+		if p, _ := GetEnv("OTEL_EXPORTER_OTLP_PROTOCOL"); p == "grpc" {
+			exp, err = otlptracegrpc.New(ctx)
+		} else {
+			exp, err = otlptracehttp.New(ctx)
+		}
+	case "stdout":
+		exp, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	default:
+		return nil, fmt.Errorf("expected OTEL_TRACES_EXPORTER %q to be one of `otlp`, `stdout`, or `none`", e)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exp), sdktrace.WithResource(resource.Default()))
+	otel.SetTracerProvider(tp)
+	Infof(ctx, "exporting traces via %T", exp)
+	return tp.Shutdown, nil
+}
+
+// StartSpan starts a span with the given name as a child of the span in the given context (if any).
+// Notifiers should use it to trace their template execution and outbound calls.
+func StartSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// statusRecorder records the status code of an HTTP response so that the request's span can be marked accordingly.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+// endSpan ends the given span, marking it as failed if the response status is an error.
+func (s *statusRecorder) endSpan(span trace.Span) {
+	span.SetAttributes(attribute.Int("http.response.status_code", s.status))
+	if s.status >= http.StatusBadRequest {
+		span.SetStatus(codes.Error, http.StatusText(s.status))
+	}
+	span.End()
+}
+
+// EndSpan ends the given span, marking it as failed if the given error is non-nil.
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
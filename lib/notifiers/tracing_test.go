@@ -0,0 +1,129 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifiers
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	cbpb "cloud.google.com/go/cloudbuild/apiv1/v2/cloudbuildpb"
+	"github.com/google/go-cmp/cmp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+const (
+	incomingTraceID = "0af7651916cd43dd8448eb211c80319c"
+	incomingSpanID  = "b7ad6b7169203331"
+)
+
+// tracingNotifier filters and "delivers" the Build within spans, like a real notifier would.
+type tracingNotifier struct {
+	filter EventFilter
+}
+
+func (n *tracingNotifier) SetUp(_ context.Context, _ *Config, _ string, _ SecretGetter, _ BindingResolver) error {
+	return nil
+}
+
+func (n *tracingNotifier) SendNotification(ctx context.Context, build *cbpb.Build) error {
+	if !n.filter.Apply(ctx, build) {
+		return nil
+	}
+	_, span := StartSpan(ctx, "http.Post")
+	EndSpan(span, nil)
+	return nil
+}
+
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	sr := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+	return sr
+}
+
+func TestReceiverTracing(t *testing.T) {
+	sr := recordSpans(t)
+	filter, err := MakeCELPredicate("build.status == Build.Status.SUCCESS")
+	if err != nil {
+		t.Fatalf("MakeCELPredicate failed: %v", err)
+	}
+
+	body := fmt.Sprintf(`{"message": {"data": %q, "id": "message-id"}, "subscription": "projects/p/subscriptions/s"}`,
+		base64.StdEncoding.EncodeToString([]byte(ceBuildJSON)))
+	req := httptest.NewRequest(http.MethodPost, "http://notifer.example.com/", strings.NewReader(body))
+	req.Header.Set("traceparent", fmt.Sprintf("00-%s-%s-01", incomingTraceID, incomingSpanID))
+	w := httptest.NewRecorder()
+
+	newReceiver(&tracingNotifier{filter: filter}, &receiverParams{notifierType: "tracingNotifier"})(w, req)
+	if s := w.Result().StatusCode; s != http.StatusOK {
+		t.Fatalf("result.StatusCode = %d, expected %d", s, http.StatusOK)
+	}
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	var names []string
+	for _, s := range sr.Ended() {
+		spans[s.Name()] = s
+		names = append(names, s.Name())
+		if got := s.SpanContext().TraceID().String(); got != incomingTraceID {
+			t.Errorf("span %q has trace ID %q, want the incoming %q", s.Name(), got, incomingTraceID)
+		}
+	}
+	wantNames := []string{"CELPredicate.Apply", "http.Post", "SendNotification", "ReceiveMessage"}
+	if diff := cmp.Diff(wantNames, names); diff != "" {
+		t.Fatalf("unexpected span names diff: (want- got+)\n%s", diff)
+	}
+
+	for child, parent := range map[string]string{
+		"CELPredicate.Apply": "SendNotification",
+		"http.Post":          "SendNotification",
+		"SendNotification":   "ReceiveMessage",
+	} {
+		if got, want := spans[child].Parent().SpanID(), spans[parent].SpanContext().SpanID(); got != want {
+			t.Errorf("span %q has parent %v, want %q (%v)", child, got, parent, want)
+		}
+	}
+	if got := spans["ReceiveMessage"].Parent().SpanID().String(); got != incomingSpanID {
+		t.Errorf("ReceiveMessage span has parent %q, want the incoming %q", got, incomingSpanID)
+	}
+}
+
+func TestReceiverTracingFailure(t *testing.T) {
+	sr := recordSpans(t)
+	req := httptest.NewRequest(http.MethodPost, "http://notifer.example.com/", strings.NewReader("not JSON"))
+	w := httptest.NewRecorder()
+
+	newReceiver(new(eventRecordingNotifier), &receiverParams{})(w, req)
+	if s := w.Result().StatusCode; s != http.StatusBadRequest {
+		t.Fatalf("result.StatusCode = %d, expected %d", s, http.StatusBadRequest)
+	}
+
+	ended := sr.Ended()
+	if len(ended) != 1 {
+		t.Fatalf("got %d spans, want 1", len(ended))
+	}
+	if got := ended[0].Status().Code; got != codes.Error {
+		t.Errorf("ReceiveMessage span has status %v, want %v", got, codes.Error)
+	}
+}
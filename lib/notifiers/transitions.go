@@ -0,0 +1,250 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifiers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	cbpb "cloud.google.com/go/cloudbuild/apiv1/v2/cloudbuildpb"
+)
+
+// Transition kinds.
+const (
+	// TransitionFirst is the first passing or failing Build of its trigger and branch.
+	TransitionFirst = "FIRST"
+	// TransitionBroken is a failing Build after a passing one.
+	TransitionBroken = "BROKEN"
+	// TransitionFixed is a passing Build after a failing one.
+	TransitionFixed = "FIXED"
+	// TransitionStillFailing is a failing Build after a failing one.
+	TransitionStillFailing = "STILL_FAILING"
+	// TransitionStillPassing is a passing Build after a passing one.
+	TransitionStillPassing = "STILL_PASSING"
+)
+
+// TransitionsConfig is the data container for configuring where the last status of every trigger and branch is kept.
+type TransitionsConfig struct {
+	// Type is either `memory` (the default) or `file`.
+	Type string `yaml:"type"`
+	// Path is the local file that the statuses are persisted to when Type is `file`.
+	Path string `yaml:"path"`
+}
+
+// Transition describes how the status of a Build compares to that of the previous Build of its trigger and branch.
+type Transition struct {
+	// Kind is one of the Transition* constants, or empty if the Build is neither passing nor failing (e.g. because
+	// it is still running or was cancelled).
+	Kind string `json:"Kind,omitempty"`
+	// PreviousStatus is the status of the previous passing or failing Build, or STATUS_UNKNOWN if there was none.
+	PreviousStatus cbpb.Build_Status `json:"PreviousStatus"`
+	// PreviousBuildID is the ID of the previous passing or failing Build.
+	PreviousBuildID string `json:"PreviousBuildID,omitempty"`
+}
+
+type transitionKey struct{}
+
+// TransitionFromContext returns the Transition of the Build that is being sent, or nil if transitions are not
+// tracked or the Build has no trigger.
+func TransitionFromContext(ctx context.Context) *Transition {
+	t, _ := ctx.Value(transitionKey{}).(*Transition)
+	return t
+}
+
+func withTransition(ctx context.Context, t *Transition) context.Context {
+	return context.WithValue(ctx, transitionKey{}, t)
+}
+
+// lastStatus is the status of the latest passing or failing Build of a trigger and branch.
+type lastStatus struct {
+	Status     cbpb.Build_Status `json:"status"`
+	BuildID    string            `json:"buildId"`
+	FinishTime time.Time         `json:"finishTime"`
+}
+
+// statusStore remembers the lastStatus of every trigger and branch.
+type statusStore interface {
+	// Get returns the lastStatus of the given key, or nil if there is none.
+	Get(ctx context.Context, key string) (*lastStatus, error)
+	// Put records the lastStatus of the given key.
+	Put(ctx context.Context, key string, s *lastStatus) error
+}
+
+// passing and failing return true iff the given status counts as a passing or a failing Build.
+// Other statuses (e.g. CANCELLED) neither change nor continue a streak.
+func passing(s cbpb.Build_Status) bool {
+	return s == cbpb.Build_SUCCESS
+}
+
+func failing(s cbpb.Build_Status) bool {
+	return s == cbpb.Build_FAILURE || s == cbpb.Build_INTERNAL_ERROR || s == cbpb.Build_TIMEOUT
+}
+
+// statusKey returns the key of the trigger and branch of the given Build, or false if it has no trigger.
+func statusKey(build *cbpb.Build) (string, bool) {
+	if build.GetBuildTriggerId() == "" {
+		return "", false
+	}
+	return fmt.Sprintf("%s/%s", build.GetBuildTriggerId(), build.GetSubstitutions()["BRANCH_NAME"]), true
+}
+
+// transition looks up the Transition of the given Build. Errors from the store are logged and otherwise ignored, so
+// that a broken store never blocks notifications.
+func transition(ctx context.Context, s statusStore, build *cbpb.Build) *Transition {
+	key, ok := statusKey(build)
+	if !ok {
+		return nil
+	}
+	last, err := s.Get(ctx, key)
+	if err != nil {
+		Warningf(ctx, "failed to look up the last status of %q: %v", key, err)
+		return nil
+	}
+
+	t := new(Transition)
+	if last != nil {
+		t.PreviousStatus, t.PreviousBuildID = last.Status, last.BuildID
+	}
+	switch st := build.GetStatus(); {
+	case !passing(st) && !failing(st):
+	case last == nil:
+		t.Kind = TransitionFirst
+	case passing(st) && passing(last.Status):
+		t.Kind = TransitionStillPassing
+	case passing(st):
+		t.Kind = TransitionFixed
+	case failing(last.Status):
+		t.Kind = TransitionStillFailing
+	default:
+		t.Kind = TransitionBroken
+	}
+	return t
+}
+
+// recordStatus records the status of the given Build if it is passing or failing, unless a Build of the same trigger
+// and branch that finished later was already recorded. Errors are logged.
+func recordStatus(ctx context.Context, s statusStore, build *cbpb.Build) {
+	key, ok := statusKey(build)
+	st := build.GetStatus()
+	if !ok || (!passing(st) && !failing(st)) {
+		return
+	}
+
+	ls := &lastStatus{Status: st, BuildID: build.GetId(), FinishTime: build.GetFinishTime().AsTime()}
+	if last, err := s.Get(ctx, key); err == nil && last != nil && last.FinishTime.After(ls.FinishTime) {
+		Debugf(ctx, "not recording the status of Build %q since Build %q of %q finished later", ls.BuildID, last.BuildID, key)
+		return
+	}
+	if err := s.Put(ctx, key, ls); err != nil {
+		Warningf(ctx, "failed to record the status of %q: %v", key, err)
+	}
+}
+
+func validateTransitionsConfig(cfg *TransitionsConfig) error {
+	switch cfg.Type {
+	case "", "memory":
+	case "file":
+		if cfg.Path == "" {
+			return errors.New("expected transitions path to be present for the `file` type")
+		}
+	default:
+		return fmt.Errorf("expected transitions type %q to be one of `memory` or `file`", cfg.Type)
+	}
+	return nil
+}
+
+// newStatusStore returns the statusStore for the given config.
+func newStatusStore(cfg *TransitionsConfig) (statusStore, error) {
+	if err := validateTransitionsConfig(cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Type == "file" {
+		return newFileStatusStore(cfg.Path)
+	}
+	return newMemoryStatusStore(), nil
+}
+
+// memoryStatusStore is an in-memory statusStore.
+type memoryStatusStore struct {
+	mtx      sync.Mutex
+	statuses map[string]*lastStatus
+}
+
+func newMemoryStatusStore() *memoryStatusStore {
+	return &memoryStatusStore{statuses: map[string]*lastStatus{}}
+}
+
+func (m *memoryStatusStore) Get(_ context.Context, key string) (*lastStatus, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	return m.statuses[key], nil
+}
+
+func (m *memoryStatusStore) Put(_ context.Context, key string, s *lastStatus) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.statuses[key] = s
+	return nil
+}
+
+// fileStatusStore is a memoryStatusStore that rewrites a local JSON file on every change, so that the statuses
+// survive restarts. There is one entry per trigger and branch, so the file stays small.
+type fileStatusStore struct {
+	*memoryStatusStore
+	path string
+}
+
+func newFileStatusStore(path string) (*fileStatusStore, error) {
+	f := &fileStatusStore{memoryStatusStore: newMemoryStatusStore(), path: path}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transitions file %q: %w", path, err)
+	}
+	if err := json.Unmarshal(b, &f.statuses); err != nil {
+		return nil, fmt.Errorf("failed to decode transitions file %q: %w", path, err)
+	}
+	if f.statuses == nil {
+		f.statuses = map[string]*lastStatus{}
+	}
+	return f, nil
+}
+
+func (f *fileStatusStore) Put(_ context.Context, key string, s *lastStatus) error {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	f.statuses[key] = s
+	b, err := json.Marshal(f.statuses)
+	if err != nil {
+		return fmt.Errorf("failed to encode transitions: %w", err)
+	}
+	tmp := f.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return fmt.Errorf("failed to write transitions file %q: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, f.path); err != nil {
+		return fmt.Errorf("failed to replace transitions file %q: %w", f.path, err)
+	}
+	return nil
+}
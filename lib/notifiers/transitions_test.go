@@ -0,0 +1,177 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifiers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	cbpb "cloud.google.com/go/cloudbuild/apiv1/v2/cloudbuildpb"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// transitionRecordingNotifier records the Transition kind of every Build that it is asked to send, and fails while
+// fail is set.
+type transitionRecordingNotifier struct {
+	kinds []string
+	fail  bool
+}
+
+func (r *transitionRecordingNotifier) SetUp(_ context.Context, _ *Config, _ string, _ SecretGetter, _ BindingResolver) error {
+	return nil
+}
+
+func (r *transitionRecordingNotifier) SendNotification(ctx context.Context, _ *cbpb.Build) error {
+	kind := "<nil>"
+	if t := TransitionFromContext(ctx); t != nil {
+		kind = fmt.Sprintf("%s after %s", t.Kind, t.PreviousStatus)
+	}
+	r.kinds = append(r.kinds, kind)
+	if r.fail {
+		return errors.New("failed to send")
+	}
+	return nil
+}
+
+func TestTransitions(t *testing.T) {
+	n := new(transitionRecordingNotifier)
+	params := &receiverParams{notifierType: "transitionRecordingNotifier", statuses: newMemoryStatusStore()}
+	finish := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	for i, step := range []struct {
+		trigger, branch, status string
+		fail                    bool
+		want                    string
+	}{
+		{trigger: "t1", branch: "main", status: "SUCCESS", want: "FIRST after STATUS_UNKNOWN"},
+		{trigger: "t1", branch: "main", status: "SUCCESS", want: "STILL_PASSING after SUCCESS"},
+		{trigger: "t1", branch: "main", status: "FAILURE", want: "BROKEN after SUCCESS"},
+		// Builds that are neither passing nor failing have no kind and do not change the streak.
+		{trigger: "t1", branch: "main", status: "CANCELLED", want: " after FAILURE"},
+		{trigger: "t1", branch: "main", status: "TIMEOUT", want: "STILL_FAILING after FAILURE"},
+		// Every branch has its own streak.
+		{trigger: "t1", branch: "dev", status: "FAILURE", want: "FIRST after STATUS_UNKNOWN"},
+		// A failed delivery does not record the status, so that the redelivery sees the same transition.
+		{trigger: "t1", branch: "main", status: "SUCCESS", fail: true, want: "FIXED after TIMEOUT"},
+		{trigger: "t1", branch: "main", status: "SUCCESS", want: "FIXED after TIMEOUT"},
+		{trigger: "t1", branch: "main", status: "SUCCESS", want: "STILL_PASSING after SUCCESS"},
+		// Builds without a trigger have no Transition.
+		{status: "FAILURE", want: "<nil>"},
+	} {
+		n.fail = step.fail
+		finish = finish.Add(time.Minute)
+		data := fmt.Sprintf(`{"id": "build-%d", "status": %q, "buildTriggerId": %q, "substitutions": {"BRANCH_NAME": %q}, "finishTime": %q}`,
+			i, step.status, step.trigger, step.branch, finish.Format(time.RFC3339))
+		handleMessage(context.Background(), n, params, &pubSubPushMessage{Data: []byte(data), ID: fmt.Sprintf("message-%d", i)}, nil)
+
+		if got := n.kinds[len(n.kinds)-1]; got != step.want {
+			t.Errorf("step %d: got transition %q, want %q", i, got, step.want)
+		}
+	}
+}
+
+func TestRecordStatusKeepsLatest(t *testing.T) {
+	ctx := context.Background()
+	s := newMemoryStatusStore()
+	finish := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	build := func(id string, status cbpb.Build_Status, finish time.Time) *cbpb.Build {
+		b := &cbpb.Build{Id: id, Status: status, BuildTriggerId: "t1", Substitutions: map[string]string{"BRANCH_NAME": "main"}}
+		b.FinishTime = timestamppb.New(finish)
+		return b
+	}
+
+	recordStatus(ctx, s, build("later", cbpb.Build_FAILURE, finish.Add(time.Minute)))
+	// A Build that finished earlier but is delivered later must not overwrite the status.
+	recordStatus(ctx, s, build("earlier", cbpb.Build_SUCCESS, finish))
+
+	got, err := s.Get(ctx, "t1/main")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if got.BuildID != "later" || got.Status != cbpb.Build_FAILURE {
+		t.Errorf("got last status %+v, want Build %q with status FAILURE", got, "later")
+	}
+}
+
+func TestFileStatusStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "transitions.json")
+
+	s, err := newStatusStore(&TransitionsConfig{Type: "file", Path: path})
+	if err != nil {
+		t.Fatalf("newStatusStore failed: %v", err)
+	}
+	want := &lastStatus{Status: cbpb.Build_FAILURE, BuildID: "build-id", FinishTime: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)}
+	if err := s.Put(ctx, "t1/main", want); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	// The statuses survive a restart.
+	s, err = newStatusStore(&TransitionsConfig{Type: "file", Path: path})
+	if err != nil {
+		t.Fatalf("newStatusStore failed: %v", err)
+	}
+	got, err := s.Get(ctx, "t1/main")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected last status diff: (want- got+)\n%s", diff)
+	}
+	if got, err := s.Get(ctx, "t1/dev"); err != nil || got != nil {
+		t.Errorf("Get of an unknown key returned (%v, %v), want (nil, nil)", got, err)
+	}
+}
+
+func TestCELTransitionVars(t *testing.T) {
+	filter, err := MakeCELPredicate(`transition == "FIXED" && previous_status == Build.Status.FAILURE`)
+	if err != nil {
+		t.Fatalf("MakeCELPredicate failed: %v", err)
+	}
+	build := &cbpb.Build{Status: cbpb.Build_SUCCESS}
+
+	for _, tc := range []struct {
+		name string
+		t    *Transition
+		want bool
+	}{{
+		name: "fixed after failure",
+		t:    &Transition{Kind: TransitionFixed, PreviousStatus: cbpb.Build_FAILURE},
+		want: true,
+	}, {
+		name: "fixed after timeout",
+		t:    &Transition{Kind: TransitionFixed, PreviousStatus: cbpb.Build_TIMEOUT},
+	}, {
+		name: "still passing",
+		t:    &Transition{Kind: TransitionStillPassing, PreviousStatus: cbpb.Build_SUCCESS},
+	}, {
+		name: "not tracked",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tc.t != nil {
+				ctx = withTransition(ctx, tc.t)
+			}
+			if got := filter.Apply(ctx, build); got != tc.want {
+				t.Errorf("Apply returned %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
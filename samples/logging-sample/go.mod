@@ -5,8 +5,8 @@ go 1.15
 require (
 	cloud.google.com/go/cloudbuild v1.10.0
 	github.com/GoogleCloudPlatform/cloud-build-notifiers/lib/notifiers v0.0.0-20210219212036-163c92a64b27
-	github.com/golang/glog v1.2.4
+	github.com/golang/glog v1.2.4 // indirect
 	github.com/rogpeppe/go-internal v1.10.0 // indirect
-	google.golang.org/protobuf v1.33.0
+	google.golang.org/protobuf v1.33.0 // indirect
 
 )
@@ -22,8 +22,6 @@ import (
 
 	cbpb "cloud.google.com/go/cloudbuild/apiv1/v2/cloudbuildpb"
 	"github.com/GoogleCloudPlatform/cloud-build-notifiers/lib/notifiers"
-	log "github.com/golang/glog"
-	"google.golang.org/protobuf/encoding/prototext"
 )
 
 // [END cloudbuild_logging_sample_imports]
@@ -31,7 +29,7 @@ import (
 // [START cloudbuild_logging_sample_main_func]
 func main() {
 	if err := notifiers.Main(new(logger)); err != nil {
-		log.Fatalf("fatal error: %v", err)
+		notifiers.Fatalf(context.Background(), "fatal error: %v", err)
 	}
 }
 
@@ -58,9 +56,9 @@ func (h *logger) SendNotification(ctx context.Context, build *cbpb.Build) error
 	// Include custom functionality here.
 	// This example logs the build.
 	if h.filter.Apply(ctx, build) {
-		log.V(1).Infof("printing build\n%s", prototext.Format(build))
+		notifiers.Infof(ctx, "printing build %s", notifiers.FormatBuild(build))
 	} else {
-		log.V(1).Infof("build (%q, %q) did NOT match CEL filter", build.ProjectId, build.Id)
+		notifiers.Debugf(ctx, "build (%q, %q) did NOT match CEL filter", build.ProjectId, build.Id)
 	}
 
 	return nil
@@ -17,13 +17,14 @@ package main
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"text/template"
-	"strings"
 
 	cbpb "cloud.google.com/go/cloudbuild/apiv1/v2/cloudbuildpb"
 	"github.com/GoogleCloudPlatform/cloud-build-notifiers/lib/notifiers"
-	log "github.com/golang/glog"
 	"github.com/slack-go/slack"
 )
 
@@ -33,7 +34,7 @@ const (
 
 func main() {
 	if err := notifiers.Main(new(slackNotifier)); err != nil {
-		log.Fatalf("fatal error: %v", err)
+		notifiers.Fatalf(context.Background(), "fatal error: %v", err)
 	}
 }
 
@@ -65,11 +66,7 @@ func (s *slackNotifier) SetUp(ctx context.Context, cfg *notifiers.Config, blockK
 		return fmt.Errorf("failed to get token secret: %w", err)
 	}
 	s.webhookURL = wu
-	tmpl, err := template.New("blockkit_template").Funcs(template.FuncMap{
-		"replace": func(s, old, new string) string {
-			return strings.ReplaceAll(s, old, new)
-		},
-	}).Parse(blockKitTemplate)
+	tmpl, err := template.New("blockkit_template").Funcs(notifiers.TemplateFuncs()).Parse(blockKitTemplate)
 
 	s.tmpl = tmpl
 	s.br = br
@@ -83,25 +80,52 @@ func (s *slackNotifier) SendNotification(ctx context.Context, build *cbpb.Build)
 		return nil
 	}
 
-	log.Infof("sending Slack webhook for Build %q (status: %q)", build.Id, build.Status)
+	notifiers.Infof(ctx, "sending Slack webhook for Build %q (status: %q)", build.Id, build.Status)
 
-	bindings, err := s.br.Resolve(ctx, nil, build)
+	msg, err := s.message(ctx, build)
 	if err != nil {
-		return fmt.Errorf("failed to resolve bindings: %w", err)
+		return err
 	}
 
-	s.tmplView = &notifiers.TemplateView{
-		Build:  &notifiers.BuildView{Build: build},
-		Params: bindings,
+	_, span := notifiers.StartSpan(ctx, "slack.PostWebhook")
+	err = slack.PostWebhookCustomHTTPContext(ctx, s.webhookURL, notifiers.DeliveryClient(), msg)
+	notifiers.EndSpan(span, err)
+	if err != nil {
+		var sce slack.StatusCodeError
+		if errors.As(err, &sce) && (sce.Code == http.StatusUnauthorized || sce.Code == http.StatusForbidden) {
+			return fmt.Errorf("failed to post Slack webhook: %w", errors.Join(err, notifiers.ErrUnauthorized))
+		}
+		return err
 	}
+	return nil
+}
 
-	msg, err := s.writeMessage()
+// Render returns the JSON of the webhook message for the given Build.
+func (s *slackNotifier) Render(ctx context.Context, build *cbpb.Build) ([]byte, error) {
+	msg, err := s.message(ctx, build)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(msg)
+}
 
+// message resolves the bindings of the given Build and writes its webhook message.
+func (s *slackNotifier) message(ctx context.Context, build *cbpb.Build) (*slack.WebhookMessage, error) {
+	bindings, err := s.br.Resolve(ctx, nil, build)
 	if err != nil {
-		return fmt.Errorf("failed to write Slack message: %w", err)
+		return nil, fmt.Errorf("failed to resolve bindings: %w", err)
 	}
 
-	return slack.PostWebhook(s.webhookURL, msg)
+	s.tmplView = notifiers.NewTemplateView(ctx, build, bindings)
+
+	_, span := notifiers.StartSpan(ctx, "template.Execute")
+	msg, err := s.writeMessage()
+	notifiers.EndSpan(span, err)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to write Slack message: %w", err)
+	}
+	return msg, nil
 }
 
 func (s *slackNotifier) writeMessage() (*slack.WebhookMessage, error) {
@@ -112,15 +136,7 @@ func (s *slackNotifier) writeMessage() (*slack.WebhookMessage, error) {
 		return nil, fmt.Errorf("failed to add UTM params: %w", err)
 	}
 
-	var clr string
-	switch build.Status {
-	case cbpb.Build_SUCCESS:
-		clr = "#22bb33"
-	case cbpb.Build_FAILURE, cbpb.Build_INTERNAL_ERROR, cbpb.Build_TIMEOUT:
-		clr = "#bb2124"
-	default:
-		clr = "#f0ad4e"
-	}
+	clr := notifiers.StatusColor(build.Status)
 
 	var buf bytes.Buffer
 	if err := s.tmpl.Execute(&buf, s.tmplView); err != nil {
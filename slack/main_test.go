@@ -3,7 +3,6 @@ package main
 import (
 	"testing"
 	"text/template"
-	"strings"
 
 	cbpb "cloud.google.com/go/cloudbuild/apiv1/v2/cloudbuildpb"
 	"github.com/GoogleCloudPlatform/cloud-build-notifiers/lib/notifiers"
@@ -66,11 +65,7 @@ func TestWriteMessage(t *testing.T) {
 		}
 	  ]`
 
-	tmpl, err := template.New("blockkit_template").Funcs(template.FuncMap{
-		"replace": func(s, old, new string) string {
-			return strings.ReplaceAll(s, old, new)
-		},
-	}).Parse(blockKitTemplate)
+	tmpl, err := template.New("blockkit_template").Funcs(notifiers.TemplateFuncs()).Parse(blockKitTemplate)
 	if err != nil {
 		t.Fatalf("failed to parse template: %v", err)
 	}
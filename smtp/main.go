@@ -17,17 +17,18 @@ package main
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	htmlTemplate "html/template"
 	textTemplate "text/template"
 	"mime/quotedprintable"
+	"net"
 	"net/smtp"
+	"net/textproto"
 	"strings"
 
 	cbpb "cloud.google.com/go/cloudbuild/apiv1/v2/cloudbuildpb"
 	"github.com/GoogleCloudPlatform/cloud-build-notifiers/lib/notifiers"
-	log "github.com/golang/glog"
-	"google.golang.org/protobuf/encoding/prototext"
 )
 
 const (
@@ -36,7 +37,7 @@ const (
 
 func main() {
 	if err := notifiers.Main(new(smtpNotifier)); err != nil {
-		log.Fatalf("fatal error: %v", err)
+		notifiers.Fatalf(context.Background(), "fatal error: %v", err)
 	}
 }
 
@@ -60,14 +61,14 @@ func (s *smtpNotifier) SetUp(ctx context.Context, cfg *notifiers.Config, cfgTemp
 		return fmt.Errorf("failed to create CELPredicate: %w", err)
 	}
 	s.filter = prd
-	htmlTmpl, err := htmlTemplate.New("email_template").Parse(cfgTemplate)
+	htmlTmpl, err := htmlTemplate.New("email_template").Funcs(notifiers.TemplateFuncs()).Parse(cfgTemplate)
 	if err != nil {
 		return fmt.Errorf("failed to parse HTML email template: %w", err)
 	}
 	s.htmlTmpl = htmlTmpl
 
 	if subject, subjectFound := cfg.Spec.Notification.Delivery["subject"]; subjectFound {
-		textTmpl, err := textTemplate.New("subject_template").Parse(subject.(string))
+		textTmpl, err := textTemplate.New("subject_template").Funcs(notifiers.TemplateFuncs()).Parse(subject.(string))
 		if err != nil {
 			return fmt.Errorf("failed to parse TEXT subject template: %w", err)
 		}
@@ -145,37 +146,71 @@ func getMailConfig(ctx context.Context, sg notifiers.SecretGetter, spec *notifie
 
 func (s *smtpNotifier) SendNotification(ctx context.Context, build *cbpb.Build) error {
 	if !s.filter.Apply(ctx, build) {
-		log.V(2).Infof("no mail for event:\n%s", prototext.Format(build))
+		notifiers.Debugf(ctx, "no mail for event %s", notifiers.FormatBuild(build))
 		return nil
 	}
-	bindings, err := s.br.Resolve(ctx, nil, build)
+	s.setTemplateView(ctx, build)
+	notifiers.Infof(ctx, "sending email for (build id = %q, status = %s)", build.GetId(), build.GetStatus())
+	return s.sendSMTPNotification(ctx)
+}
+
+// Render returns the email message (headers and body) for the given Build.
+func (s *smtpNotifier) Render(ctx context.Context, build *cbpb.Build) ([]byte, error) {
+	s.setTemplateView(ctx, build)
+	email, err := s.buildEmail()
 	if err != nil {
-		log.Errorf("failed to resolve bindings :%v", err)
+		return nil, fmt.Errorf("failed to build email: %w", err)
 	}
-	s.tmplView = &notifiers.TemplateView{
-		Build:  &notifiers.BuildView{Build: build},
-		Params: bindings,
+	return []byte(email), nil
+}
+
+func (s *smtpNotifier) setTemplateView(ctx context.Context, build *cbpb.Build) {
+	bindings, err := s.br.Resolve(ctx, nil, build)
+	if err != nil {
+		notifiers.Errorf(ctx, "failed to resolve bindings :%v", err)
 	}
-	log.Infof("sending email for (build id = %q, status = %s)", build.GetId(), build.GetStatus())
-	return s.sendSMTPNotification()
+	s.tmplView = notifiers.NewTemplateView(ctx, build, bindings)
 }
 
-func (s *smtpNotifier) sendSMTPNotification() error {
+func (s *smtpNotifier) sendSMTPNotification(ctx context.Context) error {
+	_, span := notifiers.StartSpan(ctx, "template.Execute")
 	email, err := s.buildEmail()
+	notifiers.EndSpan(span, err)
 	if err != nil {
-		log.Warningf("failed to build email: %v", err)
+		notifiers.Warningf(ctx, "failed to build email: %v", err)
 	}
 
 	addr := fmt.Sprintf("%s:%s", s.mcfg.server, s.mcfg.port)
+	if notifiers.DryRun() {
+		return notifiers.CaptureDelivery(ctx, "smtp", fmt.Sprintf("%s to %s", addr, strings.Join(s.mcfg.recipients, ",")), []byte(email))
+	}
 	auth := smtp.PlainAuth("", s.mcfg.sender, s.mcfg.password, s.mcfg.server)
 
-	if err = smtp.SendMail(addr, auth, s.mcfg.from, s.mcfg.recipients, []byte(email)); err != nil {
+	_, span = notifiers.StartSpan(ctx, "smtp.SendMail")
+	err = smtp.SendMail(addr, auth, s.mcfg.from, s.mcfg.recipients, []byte(email))
+	notifiers.EndSpan(span, err)
+	if err != nil {
+		// 535 is the SMTP reply code for rejected credentials.
+		var tpe *textproto.Error
+		if errors.As(err, &tpe) && tpe.Code == 535 {
+			return fmt.Errorf("failed to send email: %w", errors.Join(err, notifiers.ErrUnauthorized))
+		}
 		return fmt.Errorf("failed to send email: %w", err)
 	}
-	log.V(2).Infoln("email sent successfully")
+	notifiers.Debugf(ctx, "email sent successfully")
 	return nil
 }
 
+// CheckHealth returns an error iff the SMTP server cannot be dialed.
+func (s *smtpNotifier) CheckHealth(ctx context.Context) error {
+	addr := fmt.Sprintf("%s:%s", s.mcfg.server, s.mcfg.port)
+	conn, err := new(net.Dialer).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to dial SMTP server %q: %w", addr, err)
+	}
+	return conn.Close()
+}
+
 func (s *smtpNotifier) buildEmail() (string, error) {
 	build := s.tmplView.Build
 	logURL, err := notifiers.AddUTMParams(s.tmplView.Build.LogUrl, notifiers.EmailMedium)